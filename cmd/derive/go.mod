@@ -0,0 +1,12 @@
+module github.com/phorne-uncharted/bigearth-processor/cmd/derive
+
+go 1.13
+
+require (
+	github.com/phorne-uncharted/bigearth-processor v0.0.0-20200511222104-718c335d1d02
+	github.com/pkg/errors v0.9.1
+	github.com/unchartedsoftware/plog v0.0.0-20170413154239-34d2bbd3c0a9
+	github.com/urfave/cli v1.22.4
+)
+
+replace github.com/phorne-uncharted/bigearth-processor => ../../
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"io"
+	"os"
+	"runtime"
+
+	"github.com/phorne-uncharted/bigearth-processor/model"
+	"github.com/phorne-uncharted/bigearth-processor/process"
+	"github.com/pkg/errors"
+	log "github.com/unchartedsoftware/plog"
+	"github.com/urfave/cli"
+)
+
+func main() {
+
+	runtime.GOMAXPROCS(runtime.NumCPU())
+
+	app := cli.NewApp()
+	app.Name = "bigearth-deriver"
+	app.Version = "0.1.0"
+	app.Usage = "Derive new bands or masks from the bands of big earth captures using band math"
+	app.UsageText = "bigearth-deriver --expression=<expression> --name=<name> --source=<filepath> --destination=<filepath>"
+	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "source",
+			Value: "",
+			Usage: "The folder containing all big earth captures or multiband images",
+		},
+		cli.StringFlag{
+			Name:  "destination",
+			Value: "",
+			Usage: "The folder to write the derived bands",
+		},
+		cli.StringFlag{
+			Name:  "expression",
+			Value: "",
+			Usage: "Band math expression evaluated on reflectance, such as (B08-B04)/(B08+B04+0.5) or B11/B12 > 1.2",
+		},
+		cli.StringFlag{
+			Name:  "name",
+			Value: "EXPR",
+			Usage: "The band name of the derived band",
+		},
+		cli.StringFlag{
+			Name:  "band-mapping",
+			Value: "",
+			Usage: "CSV list of multiband image bands to name in the format (band):(name)",
+		},
+		cli.IntFlag{
+			Name:  "log-frequency",
+			Value: 1000,
+			Usage: "Output log every X tiles",
+		},
+		cli.StringFlag{
+			Name:  "file-list",
+			Value: "",
+			Usage: "Text file listing the entries to process one per line, used instead of reading the source folder",
+		},
+		cli.IntFlag{
+			Name:  "batch-size",
+			Value: process.DefaultBatchSize,
+			Usage: "The number of source folder entries read at once",
+		},
+	}
+	app.Action = func(c *cli.Context) error {
+		if c.String("source") == "" {
+			return cli.NewExitError("missing commandline flag `--source`", 1)
+		}
+		if c.String("destination") == "" {
+			return cli.NewExitError("missing commandline flag `--destination`", 1)
+		}
+		if c.String("expression") == "" {
+			return cli.NewExitError("missing commandline flag `--expression`", 1)
+		}
+
+		source := c.String("source")
+		destination := c.String("destination")
+		logFrequency := c.Int("log-frequency")
+
+		bandMapping, err := model.ParseBandMapping("", c.String("band-mapping"))
+		if err != nil {
+			log.Errorf("%v", err)
+			return cli.NewExitError(errors.Cause(err), 1)
+		}
+
+		err = processFolder(source, c.String("file-list"), c.Int("batch-size"), destination, c.String("name"), c.String("expression"), bandMapping, logFrequency)
+		if err != nil {
+			log.Errorf("%v", err)
+			return cli.NewExitError(errors.Cause(err), 2)
+		}
+
+		return nil
+	}
+	// run app
+	app.Run(os.Args)
+}
+
+func processFolder(folder string, fileList string, batchSize int, destinationRoot string, name string, expressionSource string, bandMapping map[int]string, logFrequency int) error {
+	os.MkdirAll(destinationRoot, os.ModePerm)
+
+	log.Infof("deriving band '%s' = '%s' for captures found in '%s', outputting to '%s'", name, expressionSource, folder, destinationRoot)
+	source, err := process.NewSource(folder, fileList, batchSize)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	var expression *model.Expression
+	count := 0
+	skipped := 0
+	for {
+		capture, err := source.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		var tile *model.Tile
+		if capture.IsDir() {
+			tile = model.NewTile(capture.Folder, capture.Name)
+		} else {
+			tile = model.NewTileMultiBand(capture.Path())
+			tile.BandMapping = bandMapping
+		}
+
+		err = tile.LoadFiles()
+		if err != nil {
+			return err
+		}
+
+		// parse the expression against the bands of the first tile
+		if expression == nil {
+			expression, err = model.ParseExpression(name, expressionSource, tile.BandNames())
+			if err != nil {
+				return err
+			}
+			log.Infof("parsed expression using bands %v (mask: %v)", expression.Bands, expression.IsMask())
+		}
+
+		// tiles without the bands of the expression, such as Sentinel-1 tiles, are skipped
+		if len(tile.MissingBands(expression.Bands)) > 0 {
+			skipped++
+			continue
+		}
+
+		img, err := tile.EvaluateExpression(expression)
+		if err != nil {
+			return err
+		}
+
+		err = tile.WriteDerivedBand(destinationRoot, img)
+		if err != nil {
+			return err
+		}
+
+		count++
+		if count%logFrequency == 0 {
+			log.Infof("derived %d captures", count)
+		}
+	}
+
+	log.Infof("done deriving %d captures", count)
+	if skipped > 0 {
+		log.Warnf("skipped %d captures missing the bands used by '%s'", skipped, expressionSource)
+	}
+
+	return nil
+}
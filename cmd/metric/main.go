@@ -2,22 +2,35 @@ package main
 
 import (
 	"fmt"
-	"io/ioutil"
-	"math/rand"
 	"os"
-	"path"
 	"runtime"
-	"sort"
 
+	"github.com/phorne-uncharted/bigearth-processor/filter"
 	"github.com/phorne-uncharted/bigearth-processor/model"
+	"github.com/phorne-uncharted/bigearth-processor/process"
 	"github.com/pkg/errors"
 	log "github.com/unchartedsoftware/plog"
 	"github.com/urfave/cli"
 )
 
-type labelCount struct {
-	label string
-	count int
+type options struct {
+	source          string
+	s2Source        string
+	outputFrequency int
+	metadataOnly    bool
+	firstOnly       bool
+	bandMapping     map[int]string
+	nomenclature    *model.Nomenclature
+	labelMap        *model.LabelMap
+	rollUp          bool
+	indices         []*model.SpectralIndex
+	filters         *filter.Set
+	bySeason        bool
+	hemisphere      string
+	fileList        string
+	batchSize       int
+	workers         int
+	maxInFlight     int
 }
 
 func main() {
@@ -43,23 +56,122 @@ func main() {
 			Name:  "metadata-only",
 			Usage: "If true, only the metadata metrics will be tracked",
 		},
+		cli.StringFlag{
+			Name:  "band-mapping",
+			Value: "",
+			Usage: "CSV list of multiband image bands to name in the format (band):(name)",
+		},
+		cli.StringFlag{
+			Name:  "indices",
+			Value: "",
+			Usage: "CSV list of spectral indices (NDVI, NDWI, NDBI, NBR, ...) to include in the metrics",
+		},
 		cli.IntFlag{
 			Name:  "output-frequency",
 			Value: 10000,
 			Usage: "Output metrics every X tiles",
 		},
+		cli.BoolFlag{
+			Name:  "by-season",
+			Usage: "If true, the metrics are also output for the tiles of each season",
+		},
+		cli.StringFlag{
+			Name:  "nomenclature",
+			Value: model.NomenclatureOriginalName,
+			Usage: "The label nomenclature, either original (43 CORINE labels) or bigearthnet-19",
+		},
+		cli.StringFlag{
+			Name:  "label-map",
+			Value: "",
+			Usage: "CSV or JSON file mapping old labels to new labels after applying the nomenclature, with an empty new label dropping the label",
+		},
+		cli.StringFlag{
+			Name:  "s2-source",
+			Value: "",
+			Usage: "The folder containing the Sentinel-2 patches to join with the Sentinel-1 patches of the source folder",
+		},
+		cli.StringFlag{
+			Name:  "file-list",
+			Value: "",
+			Usage: "Text file listing the entries to process one per line, used instead of reading the source folder",
+		},
+		cli.IntFlag{
+			Name:  "batch-size",
+			Value: process.DefaultBatchSize,
+			Usage: "The number of source folder entries read at once",
+		},
+		cli.IntFlag{
+			Name:  "workers",
+			Value: runtime.NumCPU(),
+			Usage: "The number of tiles processed concurrently",
+		},
+		cli.IntFlag{
+			Name:  "max-in-flight",
+			Value: 0,
+			Usage: "The maximum number of tiles being processed at once, defaulting to 4 per worker",
+		},
 	}
+	app.Flags = append(app.Flags, filter.Flags()...)
 	app.Action = func(c *cli.Context) error {
 		if c.String("source") == "" {
 			return cli.NewExitError("missing commandline flag `--source`", 1)
 		}
 
-		source := c.String("source")
-		firstOnly := c.Bool("first-only")
-		metadataOnly := c.Bool("metadata-only")
-		outputFrequency := c.Int("output-frequency")
+		bandMapping, err := model.ParseBandMapping("", c.String("band-mapping"))
+		if err != nil {
+			log.Errorf("%v", err)
+			return cli.NewExitError(errors.Cause(err), 2)
+		}
+
+		indices, err := model.ParseIndices(c.String("indices"))
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		nomenclature, err := model.LookupNomenclature(c.String("nomenclature"))
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		labelMap, err := model.LoadLabelMap(c.String("label-map"))
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		for _, entry := range labelMap.Entries() {
+			log.Infof("mapping label '%s' to '%s'", entry[0], entry[1])
+		}
+
+		// the CORINE hierarchy only applies to the original labels
+		rollUp := nomenclature.Name == model.NomenclatureOriginalName && labelMap.Empty()
+		if !rollUp {
+			log.Infof("not reporting label levels as they require the original nomenclature without a label map")
+		}
 
-		err := processFolder(source, outputFrequency, metadataOnly, firstOnly)
+		filters, err := filter.FromContext(c, model.OriginalLabels)
+		if err != nil {
+			log.Errorf("%v", err)
+			return cli.NewExitError(errors.Cause(err), 1)
+		}
+
+		err = processFolder(&options{
+			source:          c.String("source"),
+			s2Source:        c.String("s2-source"),
+			outputFrequency: c.Int("output-frequency"),
+			metadataOnly:    c.Bool("metadata-only"),
+			firstOnly:       c.Bool("first-only"),
+			bandMapping:     bandMapping,
+			nomenclature:    nomenclature,
+			labelMap:        labelMap,
+			rollUp:          rollUp,
+			indices:         indices,
+			filters:         filters,
+			bySeason:        c.Bool("by-season"),
+			hemisphere:      c.String("hemisphere"),
+			fileList:        c.String("file-list"),
+			batchSize:       c.Int("batch-size"),
+			workers:         c.Int("workers"),
+			maxInFlight:     c.Int("max-in-flight"),
+		})
 		if err != nil {
 			log.Errorf("%v", err)
 			return cli.NewExitError(errors.Cause(err), 2)
@@ -71,59 +183,40 @@ func main() {
 	app.Run(os.Args)
 }
 
-func processFolder(folder string, outputFrequency int, metadataOnly bool, firstOnly bool) error {
-	log.Infof("processing folder '%s' (first only: %v, metadata only: %v), outputting metrics every %d", folder, firstOnly, metadataOnly, outputFrequency)
-	captures, err := ioutil.ReadDir(folder)
+func processFolder(opts *options) error {
+	log.Infof("processing folder '%s' (first only: %v, metadata only: %v, nomenclature: %s), outputting metrics every %d",
+		opts.source, opts.firstOnly, opts.metadataOnly, opts.nomenclature.Name, opts.outputFrequency)
+	source, err := process.NewSource(opts.source, opts.fileList, opts.batchSize)
 	if err != nil {
-		return errors.Wrapf(err, "unable to read contents of '%s'", folder)
+		return err
 	}
-	log.Infof("read %d captures", len(captures))
+	defer source.Close()
 
-	bandCounts := make(map[string]int)
-	labelCounts := make(map[string]int)
-	labelSingleCounts := make(map[string]int)
-	sizeCounts := make(map[string]int)
-	pixelValueCounts := make(map[uint16]int)
-	count := 0
-	for _, capture := range captures {
-		if rand.Float64() < -1 {
-			continue
-		}
+	// aggregate in order so the periodic output does not depend on timing
+	engine := process.NewEngine(opts.workers, opts.maxInFlight, true)
+	log.Infof("processing captures using %d workers", engine.Workers)
 
-		var tile *model.Tile
-		if capture.IsDir() {
-			tile = model.NewTile(folder, capture.Name())
-		} else {
-			tile = model.NewTileMultiBand(path.Join(folder, capture.Name()))
-			log.Infof("TILE: %v", tile)
-		}
-
-		if metadataOnly {
-			log.Infof("loading metadata only")
-			tile.LoadMetadata()
-		} else {
-			log.Infof("loading all files")
-			err = tile.LoadFiles()
-		}
-
-		if err != nil {
-			return err
+	totals := newMetrics()
+	seasonal := make(map[string]*metrics)
+	count := 0
+	unpaired := 0
+	err = engine.Run(source, func(item *process.Item) (interface{}, error) {
+		return processTile(item, opts)
+	}, func(item *process.Item, result interface{}) error {
+		tileMetrics := result.(*metrics)
+		if tileMetrics == nil {
+			return nil
 		}
-
-		for _, img := range tile.Images {
-			bandCounts[img.Band]++
-			sizeString := fmt.Sprintf("%d X %d", img.SizeX, img.SizeY)
-			sizeCounts[sizeString]++
-			for _, p := range img.Pixels {
-				pixelValueCounts[p]++
-			}
+		if tileMetrics.unpaired {
+			unpaired++
+			return nil
 		}
-
-		for _, label := range tile.Metadata.Labels {
-			labelCounts[label]++
-			if len(tile.Metadata.Labels) == 1 {
-				labelSingleCounts[label]++
+		totals.merge(tileMetrics)
+		if opts.bySeason {
+			if seasonal[tileMetrics.season] == nil {
+				seasonal[tileMetrics.season] = newMetrics()
 			}
+			seasonal[tileMetrics.season].merge(tileMetrics)
 		}
 
 		count++
@@ -131,118 +224,168 @@ func processFolder(folder string, outputFrequency int, metadataOnly bool, firstO
 			log.Infof("count %d tiles", count)
 		}
 
-		if count%outputFrequency == 0 {
-			outputMetrics(10000, bandCounts, labelCounts, labelSingleCounts, sizeCounts, pixelValueCounts)
-			outputMetrics(40000, bandCounts, labelCounts, labelSingleCounts, sizeCounts, pixelValueCounts)
+		if count%opts.outputFrequency == 0 {
+			totals.output(10000)
+			totals.output(40000)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	totals.output(10000)
+	totals.output(40000)
+	if opts.bySeason {
+		// tiles without an acquisition date have no season
+		for _, season := range append(model.Seasons, "") {
+			if seasonal[season] == nil {
+				continue
+			}
+			name := season
+			if name == "" {
+				name = "unknown"
+			}
+			fmt.Printf("season %s", name)
+			fmt.Println()
+			seasonal[season].output(10000)
 		}
 	}
+	if unpaired > 0 {
+		log.Warnf("skipped %d tiles without a corresponding Sentinel-2 patch", unpaired)
+	}
+	log.Infof("%s", opts.filters.Summary())
 
-	outputMetrics(10000, bandCounts, labelCounts, labelSingleCounts, sizeCounts, pixelValueCounts)
-	outputMetrics(40000, bandCounts, labelCounts, labelSingleCounts, sizeCounts, pixelValueCounts)
+	for _, entry := range opts.labelMap.Entries() {
+		fmt.Printf("label map %s: %s", entry[0], entry[1])
+		fmt.Println()
+	}
 
 	return nil
 }
 
-func outputMetrics(upperLimitPixel uint16, bandCounts map[string]int, labelCounts map[string]int, labelSingleCounts map[string]int, sizeCounts map[string]int, pixelValueCounts map[uint16]int) {
-	maxPV := uint16(0)
-	minPV := uint16(65535)
-	for pv := range pixelValueCounts {
-		if pv > maxPV {
-			maxPV = pv
-		}
-		if pv < minPV {
-			minPV = pv
-		}
+// processTile collects the metrics of a single tile, returning nil if the
+// tile is excluded. Tiles that cannot be paired with their Sentinel-2 patch
+// return metrics flagged as unpaired.
+func processTile(item *process.Item, opts *options) (*metrics, error) {
+	var tile *model.Tile
+	if item.IsDir() {
+		tile = model.NewTile(item.Folder, item.Name)
+	} else {
+		tile = model.NewTileMultiBand(item.Path())
+		tile.BandMapping = opts.bandMapping
 	}
 
-	if maxPV > upperLimitPixel {
-		maxPV = upperLimitPixel
-	}
-	pixelCounts := make([]int, (((maxPV+1)/20)+1)*20)
-	totalPixelCount := 0
-	totalPixelValue := int64(0)
-	mostCommonPixelValue := uint16(0)
-	mostCommonPixelCount := 0
-	for pv, c := range pixelValueCounts {
-		if pv > upperLimitPixel {
-			pv = upperLimitPixel
+	// multiband images do not have any metadata to load
+	var err error
+	if !tile.MultiBand && (opts.s2Source != "" || opts.metadataOnly) {
+		err = tile.LoadMetadata()
+		if err != nil {
+			return nil, err
 		}
+	}
 
-		pixelCounts[pv] += c
-		totalPixelCount += c
-		totalPixelValue += int64(c) * int64(pv)
-		if mostCommonPixelCount < c {
-			mostCommonPixelCount = c
-			mostCommonPixelValue = pv
+	if opts.s2Source != "" {
+		if tile.Metadata == nil || tile.Metadata.CorrespondingS2Patch == "" {
+			log.Warnf("skipping tile '%s' without a corresponding Sentinel-2 patch", tile.TileName)
+			return &metrics{unpaired: true}, nil
+		}
+		err = tile.PairWithS2(opts.s2Source)
+		if err != nil {
+			return nil, err
 		}
 	}
 
-	medianCount := float64(totalPixelCount) / 2.0
-	medianValue := -1.0
-	for i := 0; i < len(pixelCounts); i += 20 {
-		fmt.Println()
-		fmt.Printf("values %d-%d: %v", i, i+19, pixelCounts[i:i+20])
-		if medianValue < 0 {
-			for j := 0; j < 20; j++ {
-				medianCount = medianCount - float64(pixelCounts[i+j])
-				if medianCount <= 0 {
-					medianValue = float64(i + j)
-					break
-				} else if medianCount < 1 {
-					// assume that every value has at least 1, so median will be between 2 successive values
-					medianValue = float64(i+j) + 0.5
-					break
-				}
-			}
+	if !opts.metadataOnly {
+		err = tile.LoadFiles()
+		if err != nil {
+			return nil, err
 		}
 	}
-	fmt.Println()
-	fmt.Printf("total pixel count: %d", totalPixelCount)
-	fmt.Println()
-	fmt.Printf("total pixel value: %d", totalPixelValue)
-	fmt.Println()
-	fmt.Printf("most common pixel value: %d", mostCommonPixelValue)
-	fmt.Println()
-	fmt.Printf("most common pixel count: %d", mostCommonPixelCount)
-	fmt.Println()
-	fmt.Printf("mean pixel value: %f", float64(totalPixelValue)/float64(totalPixelCount))
-	fmt.Println()
-	fmt.Printf("median pixel value: %f", medianValue)
-	fmt.Println()
-	fmt.Printf("min pixel value: %d", minPV)
-	fmt.Println()
-	fmt.Printf("max pixel value: %d", maxPV)
-
-	for b, c := range bandCounts {
-		fmt.Println()
-		fmt.Printf("band %s: %d", b, c)
+
+	excluded, err := opts.filters.Exclude(tile)
+	if err != nil {
+		return nil, err
+	}
+	if excluded {
+		return nil, nil
 	}
 
-	for s, c := range sizeCounts {
-		fmt.Println()
-		fmt.Printf("size %s: %d", s, c)
+	m := newMetrics()
+	if opts.bySeason {
+		m.season, _ = filter.TileSeason(tile, opts.hemisphere)
+	}
+	if !opts.metadataOnly && !tile.MultiBand {
+		for _, band := range tile.MissingBands(tile.ExpectedBands()) {
+			m.missingBandCounts[band]++
+		}
 	}
 
-	outputLabels("label", labelCounts)
-	outputLabels("label single", labelSingleCounts)
-}
+	for _, img := range tile.Images {
+		m.bandCounts[img.Band]++
+		sizeString := fmt.Sprintf("%d X %d", img.SizeX, img.SizeY)
+		m.sizeCounts[sizeString]++
+		m.dataTypeCounts[img.DataType.String()]++
 
-func outputLabels(tag string, labelCounts map[string]int) {
-	labelResult := make([]*labelCount, 0)
-	for l, c := range labelCounts {
-		labelResult = append(labelResult, &labelCount{
-			label: l,
-			count: c,
-		})
+		// the pixel histogram only tracks unsigned integer values
+		switch img.DataType {
+		case model.DataTypeFloat32:
+			if m.bandStats[img.Band] == nil {
+				m.bandStats[img.Band] = newValueStats()
+			}
+			for _, p := range img.PixelsFloat32 {
+				m.bandStats[img.Band].add(float64(p))
+			}
+		case model.DataTypeUint8:
+			for _, p := range img.PixelsUint8 {
+				m.pixelValueCounts[uint16(p)]++
+			}
+		case model.DataTypeUint16:
+			for _, p := range img.PixelsUint16 {
+				m.pixelValueCounts[p]++
+			}
+		}
 	}
 
-	sort.Slice(labelResult, func(i int, j int) bool {
-		return labelResult[i].count > labelResult[j].count
-	})
+	if !opts.metadataOnly {
+		for _, index := range opts.indices {
+			// tiles without the input bands, such as Sentinel-1 tiles, are skipped for the index
+			if len(tile.MissingBands(index.Bands)) > 0 {
+				m.skippedIndices[index.Name]++
+				continue
+			}
+			img, err := tile.ComputeIndex(index)
+			if err != nil {
+				return nil, err
+			}
+			if m.indexStats[index.Name] == nil {
+				m.indexStats[index.Name] = newValueStats()
+			}
+			for _, p := range img.PixelsFloat32 {
+				m.indexStats[index.Name].add(float64(p))
+			}
+		}
+	}
 
-	for _, lr := range labelResult {
-		fmt.Println()
-		fmt.Printf("%s %s: %d", tag, lr.label, lr.count)
+	// multiband images do not have any metadata
+	if tile.Metadata != nil {
+		labels := opts.labelMap.Apply(opts.nomenclature.Convert(tile.Metadata.Labels))
+		for _, label := range labels {
+			m.labelCounts[label]++
+			if len(labels) == 1 {
+				m.labelSingleCounts[label]++
+			}
+		}
+
+		// roll the original labels up the CORINE hierarchy
+		if opts.rollUp {
+			for level, counts := range m.levelCounts {
+				for _, label := range model.RollUpLabels(tile.Metadata.Labels, level) {
+					counts[label]++
+				}
+			}
+		}
 	}
-	fmt.Println()
+
+	return m, nil
 }
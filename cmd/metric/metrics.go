@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+type labelCount struct {
+	label string
+	count int
+}
+
+type valueStats struct {
+	count int
+	sum   float64
+	min   float64
+	max   float64
+}
+
+// metrics are collected for each tile by the workers and then merged into
+// the totals.
+type metrics struct {
+	season            string
+	unpaired          bool
+	bandCounts        map[string]int
+	labelCounts       map[string]int
+	labelSingleCounts map[string]int
+	sizeCounts        map[string]int
+	dataTypeCounts    map[string]int
+	missingBandCounts map[string]int
+	bandStats         map[string]*valueStats
+	indexStats        map[string]*valueStats
+	skippedIndices    map[string]int
+	levelCounts       map[int]map[string]int
+	pixelValueCounts  map[uint16]int
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		bandCounts:        make(map[string]int),
+		labelCounts:       make(map[string]int),
+		labelSingleCounts: make(map[string]int),
+		sizeCounts:        make(map[string]int),
+		dataTypeCounts:    make(map[string]int),
+		missingBandCounts: make(map[string]int),
+		bandStats:         make(map[string]*valueStats),
+		indexStats:        make(map[string]*valueStats),
+		skippedIndices:    make(map[string]int),
+		levelCounts:       map[int]map[string]int{1: make(map[string]int), 2: make(map[string]int)},
+		pixelValueCounts:  make(map[uint16]int),
+	}
+}
+
+func (m *metrics) merge(other *metrics) {
+	mergeCounts(m.bandCounts, other.bandCounts)
+	mergeCounts(m.labelCounts, other.labelCounts)
+	mergeCounts(m.labelSingleCounts, other.labelSingleCounts)
+	mergeCounts(m.sizeCounts, other.sizeCounts)
+	mergeCounts(m.dataTypeCounts, other.dataTypeCounts)
+	mergeCounts(m.missingBandCounts, other.missingBandCounts)
+	mergeStats(m.bandStats, other.bandStats)
+	mergeStats(m.indexStats, other.indexStats)
+	mergeCounts(m.skippedIndices, other.skippedIndices)
+	for level, counts := range other.levelCounts {
+		mergeCounts(m.levelCounts[level], counts)
+	}
+	for pv, c := range other.pixelValueCounts {
+		m.pixelValueCounts[pv] += c
+	}
+}
+
+func mergeCounts(counts map[string]int, other map[string]int) {
+	for k, c := range other {
+		counts[k] += c
+	}
+}
+
+func mergeStats(stats map[string]*valueStats, other map[string]*valueStats) {
+	for k, s := range other {
+		if stats[k] == nil {
+			stats[k] = newValueStats()
+		}
+		stats[k].merge(s)
+	}
+}
+
+// output prints the metrics, capping the pixel value histogram at the upper
+// limit.
+func (m *metrics) output(upperLimitPixel uint16) {
+	maxPV := uint16(0)
+	minPV := uint16(65535)
+	for pv := range m.pixelValueCounts {
+		if pv > maxPV {
+			maxPV = pv
+		}
+		if pv < minPV {
+			minPV = pv
+		}
+	}
+
+	if maxPV > upperLimitPixel {
+		maxPV = upperLimitPixel
+	}
+	pixelCounts := make([]int, (((maxPV+1)/20)+1)*20)
+	totalPixelCount := 0
+	totalPixelValue := int64(0)
+	mostCommonPixelValue := uint16(0)
+	mostCommonPixelCount := 0
+	for pv, c := range m.pixelValueCounts {
+		if pv > upperLimitPixel {
+			pv = upperLimitPixel
+		}
+
+		pixelCounts[pv] += c
+		totalPixelCount += c
+		totalPixelValue += int64(c) * int64(pv)
+		if mostCommonPixelCount < c {
+			mostCommonPixelCount = c
+			mostCommonPixelValue = pv
+		}
+	}
+
+	medianCount := float64(totalPixelCount) / 2.0
+	medianValue := -1.0
+	for i := 0; i < len(pixelCounts); i += 20 {
+		fmt.Println()
+		fmt.Printf("values %d-%d: %v", i, i+19, pixelCounts[i:i+20])
+		if medianValue < 0 {
+			for j := 0; j < 20; j++ {
+				medianCount = medianCount - float64(pixelCounts[i+j])
+				if medianCount <= 0 {
+					medianValue = float64(i + j)
+					break
+				} else if medianCount < 1 {
+					// assume that every value has at least 1, so median will be between 2 successive values
+					medianValue = float64(i+j) + 0.5
+					break
+				}
+			}
+		}
+	}
+	fmt.Println()
+	fmt.Printf("total pixel count: %d", totalPixelCount)
+	fmt.Println()
+	fmt.Printf("total pixel value: %d", totalPixelValue)
+	fmt.Println()
+	fmt.Printf("most common pixel value: %d", mostCommonPixelValue)
+	fmt.Println()
+	fmt.Printf("most common pixel count: %d", mostCommonPixelCount)
+	fmt.Println()
+	fmt.Printf("mean pixel value: %f", float64(totalPixelValue)/float64(totalPixelCount))
+	fmt.Println()
+	fmt.Printf("median pixel value: %f", medianValue)
+	fmt.Println()
+	fmt.Printf("min pixel value: %d", minPV)
+	fmt.Println()
+	fmt.Printf("max pixel value: %d", maxPV)
+
+	for b, c := range m.bandCounts {
+		fmt.Println()
+		fmt.Printf("band %s: %d", b, c)
+	}
+
+	for s, c := range m.sizeCounts {
+		fmt.Println()
+		fmt.Printf("size %s: %d", s, c)
+	}
+
+	for dt, c := range m.dataTypeCounts {
+		fmt.Println()
+		fmt.Printf("data type %s: %d", dt, c)
+	}
+
+	for b, c := range m.missingBandCounts {
+		fmt.Println()
+		fmt.Printf("missing band %s: %d", b, c)
+	}
+
+	for b, s := range m.bandStats {
+		fmt.Println()
+		fmt.Printf("float band %s: count %d, mean %f, min %f, max %f", b, s.count, s.sum/float64(s.count), s.min, s.max)
+	}
+
+	for i, s := range m.indexStats {
+		fmt.Println()
+		fmt.Printf("index %s: count %d, mean %f, min %f, max %f", i, s.count, s.sum/float64(s.count), s.min, s.max)
+	}
+
+	for i, c := range m.skippedIndices {
+		fmt.Println()
+		fmt.Printf("index %s skipped for missing bands: %d", i, c)
+	}
+
+	outputLabels("label", m.labelCounts)
+	outputLabels("label single", m.labelSingleCounts)
+	for level := 1; level <= 2; level++ {
+		if len(m.levelCounts[level]) > 0 {
+			outputLabels(fmt.Sprintf("label level %d", level), m.levelCounts[level])
+		}
+	}
+}
+
+func outputLabels(tag string, labelCounts map[string]int) {
+	labelResult := make([]*labelCount, 0)
+	for l, c := range labelCounts {
+		labelResult = append(labelResult, &labelCount{
+			label: l,
+			count: c,
+		})
+	}
+
+	sort.Slice(labelResult, func(i int, j int) bool {
+		return labelResult[i].count > labelResult[j].count
+	})
+
+	for _, lr := range labelResult {
+		fmt.Println()
+		fmt.Printf("%s %s: %d", tag, lr.label, lr.count)
+	}
+	fmt.Println()
+}
+
+func newValueStats() *valueStats {
+	return &valueStats{min: math.Inf(1), max: math.Inf(-1)}
+}
+
+func (s *valueStats) add(value float64) {
+	if math.IsNaN(value) {
+		return
+	}
+
+	s.count++
+	s.sum += value
+	s.min = math.Min(s.min, value)
+	s.max = math.Max(s.max, value)
+}
+
+func (s *valueStats) merge(other *valueStats) {
+	s.count += other.count
+	s.sum += other.sum
+	s.min = math.Min(s.min, other.min)
+	s.max = math.Max(s.max, other.max)
+}
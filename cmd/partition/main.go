@@ -0,0 +1,442 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/phorne-uncharted/bigearth-processor/filter"
+	"github.com/phorne-uncharted/bigearth-processor/model"
+	"github.com/phorne-uncharted/bigearth-processor/process"
+	"github.com/pkg/errors"
+	log "github.com/unchartedsoftware/plog"
+	"github.com/urfave/cli"
+)
+
+const (
+	layoutCopy = "copy"
+	layoutLink = "link"
+)
+
+type options struct {
+	source       string
+	destination  string
+	layout       string
+	groupMode    string
+	blockSize    float64
+	nomenclature *model.Nomenclature
+	labelMap     *model.LabelMap
+	filters      *filter.Set
+	partitioner  *process.Partitioner
+	fileList     string
+	batchSize    int
+	workers      int
+	maxInFlight  int
+}
+
+func main() {
+
+	runtime.GOMAXPROCS(runtime.NumCPU())
+
+	app := cli.NewApp()
+	app.Name = "bigearth-partitioner"
+	app.Version = "0.1.0"
+	app.Usage = "Partition big earth captures into train, validation and test sets or k folds stratified by label"
+	app.UsageText = "bigearth-partitioner --split=<partitions> --source=<filepath> --destination=<filepath>"
+	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "source",
+			Value: "",
+			Usage: "The folder containing all big earth captures",
+		},
+		cli.StringFlag{
+			Name:  "destination",
+			Value: "",
+			Usage: "The folder to write the partition manifests and layout",
+		},
+		cli.StringFlag{
+			Name:  "split",
+			Value: "train:0.7,val:0.15,test:0.15",
+			Usage: "CSV list of partitions in the format (name):(fraction)",
+		},
+		cli.IntFlag{
+			Name:  "folds",
+			Value: 0,
+			Usage: "The number of folds for k-fold partitioning, used instead of the split",
+		},
+		cli.StringFlag{
+			Name:  "layout",
+			Value: "",
+			Usage: "If copy or link, the captures are copied or symlinked into a folder per partition",
+		},
+		cli.StringFlag{
+			Name:  "group",
+			Value: groupNone,
+			Usage: "Keep the patches of the same scene or geographic block in the same partition using none, scene or block",
+		},
+		cli.Float64Flag{
+			Name:  "block-size",
+			Value: 12000,
+			Usage: "The size in meters of the geographic blocks when grouping by block",
+		},
+		cli.Int64Flag{
+			Name:  "seed",
+			Value: 0,
+			Usage: "The seed used to order the captures of a label when partitioning",
+		},
+		cli.StringFlag{
+			Name:  "nomenclature",
+			Value: model.NomenclatureOriginalName,
+			Usage: "The label nomenclature, either original (43 CORINE labels) or bigearthnet-19",
+		},
+		cli.StringFlag{
+			Name:  "label-map",
+			Value: "",
+			Usage: "CSV or JSON file mapping old labels to new labels after applying the nomenclature, with an empty new label dropping the label",
+		},
+		cli.StringFlag{
+			Name:  "file-list",
+			Value: "",
+			Usage: "Text file listing the entries to process one per line, used instead of reading the source folder",
+		},
+		cli.IntFlag{
+			Name:  "batch-size",
+			Value: process.DefaultBatchSize,
+			Usage: "The number of source folder entries read at once",
+		},
+		cli.IntFlag{
+			Name:  "workers",
+			Value: runtime.NumCPU(),
+			Usage: "The number of tiles processed concurrently",
+		},
+		cli.IntFlag{
+			Name:  "max-in-flight",
+			Value: 0,
+			Usage: "The maximum number of tiles being processed at once, defaulting to 4 per worker",
+		},
+	}
+	app.Flags = append(app.Flags, filter.ExclusionFlags()...)
+	app.Action = func(c *cli.Context) error {
+		if c.String("source") == "" {
+			return cli.NewExitError("missing commandline flag `--source`", 1)
+		}
+		if c.String("destination") == "" {
+			return cli.NewExitError("missing commandline flag `--destination`", 1)
+		}
+		layout := strings.ToLower(c.String("layout"))
+		if layout != "" && layout != layoutCopy && layout != layoutLink {
+			return cli.NewExitError(fmt.Sprintf("unknown layout '%s' (expected %s or %s)", layout, layoutCopy, layoutLink), 1)
+		}
+
+		groupMode, err := parseGroupMode(c.String("group"))
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		if groupMode == groupBlock && c.Float64("block-size") <= 0 {
+			return cli.NewExitError("commandline flag `--block-size` must be positive", 1)
+		}
+
+		var partitions []*process.Partition
+		if c.Int("folds") > 0 {
+			partitions, err = process.FoldPartitions(c.Int("folds"))
+		} else {
+			partitions, err = process.ParsePartitions(c.String("split"))
+		}
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		// hashing the names keeps the partitions stable for a seed
+		sampler, err := process.NewSampler(1, c.Int64("seed"), process.SampleModeHash)
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		nomenclature, err := model.LookupNomenclature(c.String("nomenclature"))
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		labelMap, err := model.LoadLabelMap(c.String("label-map"))
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		filters, err := filter.ExclusionsFromContext(c)
+		if err != nil {
+			log.Errorf("%v", err)
+			return cli.NewExitError(errors.Cause(err), 1)
+		}
+
+		err = processFolder(&options{
+			source:       c.String("source"),
+			destination:  c.String("destination"),
+			layout:       layout,
+			groupMode:    groupMode,
+			blockSize:    c.Float64("block-size"),
+			nomenclature: nomenclature,
+			labelMap:     labelMap,
+			filters:      filters,
+			partitioner:  process.NewPartitioner(partitions, sampler),
+			fileList:     c.String("file-list"),
+			batchSize:    c.Int("batch-size"),
+			workers:      c.Int("workers"),
+			maxInFlight:  c.Int("max-in-flight"),
+		})
+		if err != nil {
+			log.Errorf("%v", err)
+			return cli.NewExitError(errors.Cause(err), 2)
+		}
+
+		return nil
+	}
+	// run app
+	app.Run(os.Args)
+}
+
+func processFolder(opts *options) error {
+	log.Infof("partitioning captures found in '%s' (nomenclature: %s), outputting to '%s'", opts.source, opts.nomenclature.Name, opts.destination)
+	source, err := process.NewSource(opts.source, opts.fileList, opts.batchSize)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	os.MkdirAll(opts.destination, os.ModePerm)
+	if !opts.labelMap.Empty() {
+		err := opts.labelMap.Write(path.Join(opts.destination, "label_map.json"))
+		if err != nil {
+			return err
+		}
+	}
+
+	engine := process.NewEngine(opts.workers, opts.maxInFlight, false)
+	log.Infof("reading capture labels using %d workers (grouping: %s)", engine.Workers, opts.groupMode)
+	locations := make(map[string]*location)
+	err = engine.Run(source, func(item *process.Item) (interface{}, error) {
+		return loadEntry(item, opts)
+	}, func(item *process.Item, result interface{}) error {
+		e := result.(*entry)
+		if e == nil || len(e.labels) == 0 {
+			return nil
+		}
+		if e.location != nil {
+			locations[item.Path()] = e.location
+		} else if opts.groupMode != groupNone {
+			log.Warnf("capture '%s' has no location so is not grouped", item.Name)
+		}
+		opts.partitioner.Add(item, e.labels, groupKey(e.location, opts.groupMode, opts.blockSize))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	log.Infof("%s", opts.filters.Summary())
+
+	assignments := opts.partitioner.Assign()
+	sort.Slice(assignments, func(i int, j int) bool {
+		return assignments[i].Item.Name < assignments[j].Item.Name
+	})
+	log.Infof("partitioned %d captures", len(assignments))
+
+	err = writeManifests(opts.destination, opts.partitioner.Partitions, assignments)
+	if err != nil {
+		return err
+	}
+	err = writeReport(path.Join(opts.destination, "report.csv"), opts.partitioner, len(assignments))
+	if err != nil {
+		return err
+	}
+	reportSeparation(opts.partitioner, assignments, locations)
+
+	if opts.layout != "" {
+		err = writeLayout(engine, opts, assignments)
+		if err != nil {
+			return err
+		}
+	}
+	log.Infof("done partitioning captures")
+
+	return nil
+}
+
+// loadEntry returns the labels and location of a capture, or nil if the
+// capture is excluded.
+func loadEntry(item *process.Item, opts *options) (*entry, error) {
+	tile := model.NewTile(item.Folder, item.Name)
+	err := tile.LoadMetadata()
+	if err != nil {
+		return nil, err
+	}
+
+	if tile.Metadata == nil {
+		log.Warnf("skipping capture '%s' without metadata", item.Name)
+		return nil, nil
+	}
+
+	excluded, err := opts.filters.Exclude(tile)
+	if err != nil || excluded {
+		return nil, err
+	}
+
+	return &entry{
+		labels:   opts.labelMap.Apply(opts.nomenclature.Convert(tile.Metadata.Labels)),
+		location: locate(tile),
+	}, nil
+}
+
+// writeManifests writes a CSV file per partition listing its captures, as
+// well as a CSV file listing the partition of every capture.
+func writeManifests(destination string, partitions []*process.Partition, assignments []*process.Assignment) error {
+	manifests := make(map[string][][]string)
+	all := [][]string{{"tile", "partition", "labels"}}
+	for _, p := range partitions {
+		manifests[p.Name] = [][]string{{"tile", "path", "labels"}}
+	}
+	for _, a := range assignments {
+		labels := strings.Join(a.Labels, ";")
+		manifests[a.Partition] = append(manifests[a.Partition], []string{a.Item.Name, a.Item.Path(), labels})
+		all = append(all, []string{a.Item.Name, a.Partition, labels})
+	}
+
+	for _, p := range partitions {
+		err := writeCSV(path.Join(destination, p.Name+".csv"), manifests[p.Name])
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeCSV(path.Join(destination, "partitions.csv"), all)
+}
+
+// writeReport writes and logs the number of captures with each label in
+// every partition, along with the share of the label in the partition.
+func writeReport(filename string, partitioner *process.Partitioner, total int) error {
+	header := []string{"label", "total"}
+	for _, p := range partitioner.Partitions {
+		header = append(header, p.Name)
+	}
+	rows := [][]string{header}
+	for _, label := range partitioner.Labels() {
+		counts := partitioner.LabelCounts(label)
+		labelTotal := 0
+		for _, c := range counts {
+			labelTotal += c
+		}
+
+		row := []string{label, fmt.Sprintf("%d", labelTotal)}
+		details := make([]string, len(counts))
+		for j, c := range counts {
+			row = append(row, fmt.Sprintf("%d", c))
+			details[j] = fmt.Sprintf("%s %d (%.3f)", partitioner.Partitions[j].Name, c, float64(c)/float64(labelTotal))
+		}
+		rows = append(rows, row)
+		log.Infof("label %s: %d (%s)", label, labelTotal, strings.Join(details, ", "))
+	}
+
+	return writeCSV(filename, rows)
+}
+
+func writeCSV(filename string, rows [][]string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return errors.Wrapf(err, "unable to create '%s'", filename)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	err = writer.WriteAll(rows)
+	if err != nil {
+		return errors.Wrapf(err, "unable to write '%s'", filename)
+	}
+
+	return nil
+}
+
+// writeLayout copies or links the captures into a folder per partition.
+func writeLayout(engine *process.Engine, opts *options, assignments []*process.Assignment) error {
+	partitionFolders := make(map[string]string)
+	items := make([]*process.Item, len(assignments))
+	for i, a := range assignments {
+		items[i] = a.Item
+		partitionFolders[a.Item.Path()] = path.Join(opts.destination, a.Partition)
+	}
+	for _, folder := range partitionFolders {
+		os.MkdirAll(folder, os.ModePerm)
+	}
+
+	log.Infof("writing %s layout", opts.layout)
+	return engine.Run(process.NewItemSource(items), func(item *process.Item) (interface{}, error) {
+		destination := path.Join(partitionFolders[item.Path()], item.Name)
+		if opts.layout == layoutLink {
+			return nil, link(item.Path(), destination)
+		}
+		return nil, copyFolder(item.Path(), destination)
+	}, func(item *process.Item, result interface{}) error {
+		return nil
+	})
+}
+
+func link(source string, destination string) error {
+	target, err := filepath.Abs(source)
+	if err != nil {
+		return errors.Wrapf(err, "unable to resolve '%s'", source)
+	}
+	err = os.Symlink(target, destination)
+	if err != nil && !os.IsExist(err) {
+		return errors.Wrapf(err, "unable to link '%s' to '%s'", destination, target)
+	}
+
+	return nil
+}
+
+func copyFolder(source string, destination string) error {
+	files, err := ioutil.ReadDir(source)
+	if err != nil {
+		return errors.Wrapf(err, "unable to read contents of '%s'", source)
+	}
+
+	err = os.MkdirAll(destination, os.ModePerm)
+	if err != nil {
+		return errors.Wrapf(err, "unable to make destination folder '%s'", destination)
+	}
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		err = copyFile(path.Join(source, f.Name()), path.Join(destination, f.Name()))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func copyFile(sourceFile string, destinationFile string) error {
+	in, err := os.Open(sourceFile)
+	if err != nil {
+		return errors.Wrap(err, "unable to open source file")
+	}
+	defer in.Close()
+
+	out, err := os.Create(destinationFile)
+	if err != nil {
+		return errors.Wrap(err, "unable to create destination file")
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	if err != nil {
+		return errors.Wrap(err, "unable to copy file")
+	}
+
+	return nil
+}
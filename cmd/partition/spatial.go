@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/phorne-uncharted/bigearth-processor/model"
+	"github.com/phorne-uncharted/bigearth-processor/process"
+	"github.com/pkg/errors"
+	log "github.com/unchartedsoftware/plog"
+)
+
+const (
+	groupNone  = "none"
+	groupScene = "scene"
+	groupBlock = "block"
+
+	// patchSize is the extent in meters of a BigEarth patch.
+	patchSize = 1200
+	// maxSeparation is the furthest, in patches, to look for a patch in
+	// another partition when reporting the separation.
+	maxSeparation = 10
+)
+
+// location is where a patch is, either in the coordinates of its projection
+// or, without coordinates, in patches from the origin of its scene.
+type location struct {
+	scene string
+	frame string
+	x     float64
+	y     float64
+}
+
+// entry is the labels and location of a patch.
+type entry struct {
+	labels   []string
+	location *location
+}
+
+func parseGroupMode(mode string) (string, error) {
+	mode = strings.ToLower(mode)
+	if mode == "" {
+		mode = groupNone
+	}
+	if mode != groupNone && mode != groupScene && mode != groupBlock {
+		return "", errors.Errorf("unknown group mode '%s' (expected %s, %s or %s)", mode, groupNone, groupScene, groupBlock)
+	}
+
+	return mode, nil
+}
+
+// locate finds the scene and location of a patch from its patch name, and
+// from its metadata coordinates when available.
+func locate(tile *model.Tile) *location {
+	loc := &location{
+		scene: tile.TileName,
+	}
+	id, err := tile.PatchID()
+	if err == nil {
+		loc.scene = id.Scene()
+		loc.frame = loc.scene
+		loc.x = float64(id.Col * patchSize)
+		loc.y = -float64(id.Row * patchSize)
+	}
+
+	if tile.Metadata != nil && tile.Metadata.Coordinates != nil && tile.Metadata.Projection != "" {
+		loc.frame = tile.Metadata.Projection
+		loc.x = tile.Metadata.Coordinates.ULX
+		loc.y = tile.Metadata.Coordinates.ULY
+	}
+	if loc.frame == "" {
+		return nil
+	}
+
+	return loc
+}
+
+// groupKey returns the group of a patch, with patches of the same scene or of
+// the same square block of the given size in meters grouped together.
+func groupKey(loc *location, mode string, blockSize float64) string {
+	if loc == nil || mode == groupNone {
+		return ""
+	}
+	if mode == groupScene {
+		return loc.scene
+	}
+
+	return fmt.Sprintf("%s|%d|%d", loc.frame, int64(math.Floor(loc.x/blockSize)), int64(math.Floor(loc.y/blockSize)))
+}
+
+// cell is the position of a patch in a grid of patches, with the frames
+// numbered to keep the cells cheap to hash.
+type cell struct {
+	frame int
+	x     int64
+	y     int64
+}
+
+func (l *location) cell(frames map[string]int) cell {
+	frame, ok := frames[l.frame]
+	if !ok {
+		frame = len(frames)
+		frames[l.frame] = frame
+	}
+
+	return cell{
+		frame: frame,
+		x:     int64(math.Round(l.x / patchSize)),
+		y:     int64(math.Round(l.y / patchSize)),
+	}
+}
+
+// reportSeparation logs how far each patch is from the nearest patch in
+// another partition, measured in patches, as well as how many groups each
+// partition has. Patches sharing a cell with a patch of another partition,
+// such as other acquisitions of the same location, are 0 patches apart.
+func reportSeparation(partitioner *process.Partitioner, assignments []*process.Assignment, locations map[string]*location) {
+	groupCounts := partitioner.GroupCounts()
+	for j, p := range partitioner.Partitions {
+		log.Infof("partition %s: %d groups", p.Name, groupCounts[j])
+	}
+
+	// count the patches of each partition in each cell
+	frames := make(map[string]int)
+	cells := make(map[cell]map[string]int)
+	located := 0
+	for _, a := range assignments {
+		loc := locations[a.Item.Path()]
+		if loc == nil {
+			continue
+		}
+		c := loc.cell(frames)
+		if cells[c] == nil {
+			cells[c] = make(map[string]int)
+		}
+		cells[c][a.Partition]++
+		located++
+	}
+	if located == 0 {
+		log.Warnf("no patch locations available to report the spatial separation")
+		return
+	}
+
+	// count patches by the distance to the nearest patch of another partition
+	separations := make([]int, maxSeparation+2)
+	for c, partitions := range cells {
+		for partition, count := range partitions {
+			separations[nearestOther(cells, c, partition)] += count
+		}
+	}
+
+	log.Infof("spatial separation of %d located patches:", located)
+	for d := 0; d <= maxSeparation; d++ {
+		if separations[d] > 0 {
+			log.Infof("  %d patches (%.3f) are %d patches from another partition", separations[d], float64(separations[d])/float64(located), d)
+		}
+	}
+	log.Infof("  %d patches (%.3f) are more than %d patches from another partition",
+		separations[maxSeparation+1], float64(separations[maxSeparation+1])/float64(located), maxSeparation)
+}
+
+// nearestOther returns the chebyshev distance in cells to the nearest cell
+// holding another partition, or one more than the maximum separation if there
+// is none.
+func nearestOther(cells map[cell]map[string]int, c cell, partition string) int {
+	if hasOther(cells[c], partition) {
+		return 0
+	}
+	for d := int64(1); d <= maxSeparation; d++ {
+		for dx := -d; dx <= d; dx++ {
+			for dy := -d; dy <= d; dy++ {
+				if dx != -d && dx != d && dy != -d && dy != d {
+					continue
+				}
+				if hasOther(cells[cell{frame: c.frame, x: c.x + dx, y: c.y + dy}], partition) {
+					return int(d)
+				}
+			}
+		}
+	}
+
+	return maxSeparation + 1
+}
+
+// hasOther returns true if the partitions of a cell include one other than
+// the given partition.
+func hasOther(partitions map[string]int, partition string) bool {
+	for other := range partitions {
+		if other != partition {
+			return true
+		}
+	}
+
+	return false
+}
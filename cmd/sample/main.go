@@ -2,13 +2,14 @@ package main
 
 import (
 	"io/ioutil"
-	"math/rand"
 	"os"
 	"path"
 	"regexp"
 	"runtime"
 
+	"github.com/phorne-uncharted/bigearth-processor/filter"
 	"github.com/phorne-uncharted/bigearth-processor/model"
+	"github.com/phorne-uncharted/bigearth-processor/process"
 	"github.com/pkg/errors"
 	log "github.com/unchartedsoftware/plog"
 	"github.com/urfave/cli"
@@ -23,6 +24,25 @@ type CaptureMetadata struct {
 	Labels []string `json:"labels"`
 }
 
+type options struct {
+	source       string
+	s2Source     string
+	destination  string
+	sampler      *process.Sampler
+	count        int
+	stratifier   *process.Stratifier
+	firstOnly    bool
+	singleOnly   bool
+	nomenclature *model.Nomenclature
+	labelMap     *model.LabelMap
+	indices      []*model.SpectralIndex
+	filters      *filter.Set
+	fileList     string
+	batchSize    int
+	workers      int
+	maxInFlight  int
+}
+
 func main() {
 
 	runtime.GOMAXPROCS(runtime.NumCPU())
@@ -38,6 +58,31 @@ func main() {
 			Value: 0.0001,
 			Usage: "The sample value from 0 to 1",
 		},
+		cli.Int64Flag{
+			Name:  "seed",
+			Value: 0,
+			Usage: "The seed used to sample, with 0 using a different sample every run",
+		},
+		cli.StringFlag{
+			Name:  "sample-mode",
+			Value: "",
+			Usage: "Either hash to always select the same tiles for a seed and sample value, or random, defaulting to hash when a seed is set",
+		},
+		cli.IntFlag{
+			Name:  "count",
+			Value: 0,
+			Usage: "The exact number of captures to sample, used instead of the sample value",
+		},
+		cli.StringFlag{
+			Name:  "stratify",
+			Value: "",
+			Usage: "Sample stratified by label using quota (--quota captures per label), proportional or inverse (inverse label frequency weighting) selection of --count captures",
+		},
+		cli.IntFlag{
+			Name:  "quota",
+			Value: 0,
+			Usage: "The number of captures to sample for each label when stratifying by quota",
+		},
 		cli.StringFlag{
 			Name:  "source",
 			Value: "",
@@ -56,7 +101,53 @@ func main() {
 			Name:  "single-only",
 			Usage: "If true, only consider tiles with one label",
 		},
+		cli.StringFlag{
+			Name:  "indices",
+			Value: "",
+			Usage: "CSV list of spectral indices (NDVI, NDWI, NDBI, NBR, ...) to write next to the sampled images",
+		},
+		cli.StringFlag{
+			Name:  "nomenclature",
+			Value: model.NomenclatureOriginalName,
+			Usage: "The label nomenclature, either original (43 CORINE labels) or bigearthnet-19",
+		},
+		cli.IntFlag{
+			Name:  "level",
+			Value: 3,
+			Usage: "The CORINE hierarchy level (1, 2 or 3) of the label folders when using the original nomenclature",
+		},
+		cli.StringFlag{
+			Name:  "label-map",
+			Value: "",
+			Usage: "CSV or JSON file mapping old labels to new labels after applying the nomenclature, with an empty new label dropping the label",
+		},
+		cli.StringFlag{
+			Name:  "s2-source",
+			Value: "",
+			Usage: "The folder containing the Sentinel-2 patches to join with the Sentinel-1 patches of the source folder",
+		},
+		cli.StringFlag{
+			Name:  "file-list",
+			Value: "",
+			Usage: "Text file listing the entries to process one per line, used instead of reading the source folder",
+		},
+		cli.IntFlag{
+			Name:  "batch-size",
+			Value: process.DefaultBatchSize,
+			Usage: "The number of source folder entries read at once",
+		},
+		cli.IntFlag{
+			Name:  "workers",
+			Value: runtime.NumCPU(),
+			Usage: "The number of tiles processed concurrently",
+		},
+		cli.IntFlag{
+			Name:  "max-in-flight",
+			Value: 0,
+			Usage: "The maximum number of tiles being processed at once, defaulting to 4 per worker",
+		},
 	}
+	app.Flags = append(app.Flags, filter.Flags()...)
 	app.Action = func(c *cli.Context) error {
 		if c.String("source") == "" {
 			return cli.NewExitError("missing commandline flag `--source`", 1)
@@ -65,13 +156,73 @@ func main() {
 			return cli.NewExitError("missing commandline flag `--destination`", 1)
 		}
 
-		source := c.String("source")
-		destination := c.String("destination")
-		sample := c.Float64("sample")
-		firstOnly := c.Bool("first-only")
-		singleOnly := c.Bool("single-only")
+		indices, err := model.ParseIndices(c.String("indices"))
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		nomenclature, err := model.LookupNomenclature(c.String("nomenclature"))
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		if c.Int("level") != 3 {
+			if nomenclature.Name != model.NomenclatureOriginalName {
+				return cli.NewExitError("commandline flag `--level` requires the original nomenclature", 1)
+			}
+			nomenclature, err = model.NewCorineNomenclature(c.Int("level"))
+			if err != nil {
+				return cli.NewExitError(err.Error(), 1)
+			}
+		}
+
+		labelMap, err := model.LoadLabelMap(c.String("label-map"))
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		for _, entry := range labelMap.Entries() {
+			log.Infof("mapping label '%s' to '%s'", entry[0], entry[1])
+		}
+
+		sampler, err := process.NewSampler(c.Float64("sample"), c.Int64("seed"), c.String("sample-mode"))
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		if sampler.Mode == process.SampleModeRandom && sampler.Seed != 0 {
+			log.Warnf("random sampling with a seed depends on the worker count and directory order, use hash sampling to reproduce samples")
+		}
+
+		var stratifier *process.Stratifier
+		if c.String("stratify") != "" {
+			stratifier, err = process.NewStratifier(c.String("stratify"), c.Int("quota"), c.Int("count"), sampler)
+			if err != nil {
+				return cli.NewExitError(err.Error(), 1)
+			}
+		}
+
+		filters, err := filter.FromContext(c, model.OriginalLabels)
+		if err != nil {
+			log.Errorf("%v", err)
+			return cli.NewExitError(errors.Cause(err), 1)
+		}
 
-		err := processFolder(source, destination, sample, firstOnly, singleOnly)
+		err = processFolder(&options{
+			source:       c.String("source"),
+			s2Source:     c.String("s2-source"),
+			destination:  c.String("destination"),
+			sampler:      sampler,
+			count:        c.Int("count"),
+			stratifier:   stratifier,
+			firstOnly:    c.Bool("first-only"),
+			singleOnly:   c.Bool("single-only"),
+			nomenclature: nomenclature,
+			labelMap:     labelMap,
+			indices:      indices,
+			filters:      filters,
+			fileList:     c.String("file-list"),
+			batchSize:    c.Int("batch-size"),
+			workers:      c.Int("workers"),
+			maxInFlight:  c.Int("max-in-flight"),
+		})
 		if err != nil {
 			log.Errorf("%v", err)
 			return cli.NewExitError(errors.Cause(err), 2)
@@ -83,48 +234,211 @@ func main() {
 	app.Run(os.Args)
 }
 
-func processFolder(folder string, destinationRoot string, sample float64, firstOnly bool, singleOnly bool) error {
-	os.MkdirAll(destinationRoot, os.ModePerm)
+func processFolder(opts *options) error {
+	os.MkdirAll(opts.destination, os.ModePerm)
+	if !opts.labelMap.Empty() {
+		err := opts.labelMap.Write(path.Join(opts.destination, "label_map.json"))
+		if err != nil {
+			return err
+		}
+	}
 
-	log.Infof("processing folder '%s' with sample rate %f (first only: %v, single only: %v)", folder, sample, firstOnly, singleOnly)
-	captures, err := ioutil.ReadDir(folder)
+	log.Infof("processing folder '%s' with sample rate %f (first only: %v, single only: %v, nomenclature: %s)",
+		opts.source, opts.sampler.Rate, opts.firstOnly, opts.singleOnly, opts.nomenclature.Name)
+	source, err := process.NewSource(opts.source, opts.fileList, opts.batchSize)
 	if err != nil {
-		return errors.Wrapf(err, "unable to read contents of '%s'", folder)
+		return err
 	}
-	log.Infof("read %d captures", len(captures))
+	defer source.Close()
 
-	count := 0
-	for _, capture := range captures {
-		if rand.Float64() < sample {
-			tile := model.NewTile(folder, capture.Name())
-			err = tile.LoadMetadata()
-			if err != nil {
-				return err
-			}
+	log.Infof("sampling in %s mode with seed %d", opts.sampler.Mode, opts.sampler.Seed)
+	engine := process.NewEngine(opts.workers, opts.maxInFlight, false)
+	log.Infof("processing captures using %d workers", engine.Workers)
 
-			labels := tile.Metadata.Labels
-			if singleOnly && len(labels) != 1 {
-				continue
-			}
+	preselected := opts.count > 0 || opts.stratifier != nil
+	if opts.stratifier != nil {
+		source, err = stratifyCaptures(engine, source, opts)
+	} else if opts.count > 0 {
+		source, err = selectCaptures(engine, source, opts)
+	}
+	if err != nil {
+		return err
+	}
 
-			if firstOnly {
-				labels = labels[0:1]
-			}
-			err = copyCapture(path.Join(folder, capture.Name()), destinationRoot, labels)
-			if err != nil {
-				return err
+	count := 0
+	skippedIndices := make(map[string]int)
+	err = engine.Run(source, func(item *process.Item) (interface{}, error) {
+		if !preselected && !opts.sampler.Select(item) {
+			return nil, nil
+		}
+		// preselected captures have already been filtered
+		return processCapture(item, opts, !preselected)
+	}, func(item *process.Item, result interface{}) error {
+		if captured, _ := result.(*captured); captured != nil {
+			for _, name := range captured.skippedIndices {
+				skippedIndices[name]++
 			}
-
 			count++
 			if count%10000 == 0 {
 				log.Infof("processed %d", count)
 			}
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
+	log.Infof("sampled %d captures", count)
+	for _, index := range opts.indices {
+		if skippedIndices[index.Name] > 0 {
+			log.Warnf("index %s skipped for %d captures missing its input bands", index.Name, skippedIndices[index.Name])
+		}
+	}
+	log.Infof("%s", opts.filters.Summary())
 
 	return nil
 }
 
+// selectCaptures picks exactly count captures out of those kept by the
+// filters, returning a source listing the selected captures.
+func selectCaptures(engine *process.Engine, source process.Source, opts *options) (process.Source, error) {
+	log.Infof("selecting %d captures", opts.count)
+	reservoir := process.NewReservoir(opts.count, opts.sampler)
+	// aggregate in source order so a seeded selection does not depend on timing
+	ordered := process.NewEngine(engine.Workers, engine.MaxInFlight, true)
+	err := ordered.Run(source, func(item *process.Item) (interface{}, error) {
+		tile, _, err := loadCapture(item, opts, true)
+		return tile != nil, err
+	}, func(item *process.Item, result interface{}) error {
+		if result.(bool) {
+			reservoir.Offer(item, nil)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	selected := reservoir.Selected()
+	if len(selected) < opts.count {
+		log.Warnf("only %d captures available to select %d from", len(selected), opts.count)
+	}
+	log.Infof("selected %d of %d captures", len(selected), reservoir.Offered)
+
+	items := make([]*process.Item, len(selected))
+	for i, s := range selected {
+		items[i] = s.Item
+	}
+
+	return process.NewItemSource(items), nil
+}
+
+// stratifyCaptures selects captures stratified by label out of those kept by
+// the filters, logging the achieved label distribution.
+func stratifyCaptures(engine *process.Engine, source process.Source, opts *options) (process.Source, error) {
+	log.Infof("stratifying captures by label using %s selection", opts.stratifier.Mode)
+	// aggregate in source order so a seeded selection does not depend on timing
+	ordered := process.NewEngine(engine.Workers, engine.MaxInFlight, true)
+	err := ordered.Run(source, func(item *process.Item) (interface{}, error) {
+		tile, labels, err := loadCapture(item, opts, true)
+		if tile == nil {
+			return nil, err
+		}
+		return labels, err
+	}, func(item *process.Item, result interface{}) error {
+		if result != nil {
+			opts.stratifier.Add(item, result.([]string))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	items := opts.stratifier.Select()
+	log.Infof("selected %d captures", len(items))
+	for _, stratum := range opts.stratifier.Strata() {
+		log.Infof("label %s: achieved %d, target %d (available %d)", stratum.Label, stratum.Achieved, stratum.Target, stratum.Available)
+	}
+
+	return process.NewItemSource(items), nil
+}
+
+// loadCapture loads the metadata of a capture and determines its labels,
+// returning a nil tile if the capture is excluded.
+func loadCapture(item *process.Item, opts *options, applyFilters bool) (*model.Tile, []string, error) {
+	tile := model.NewTile(item.Folder, item.Name)
+	err := tile.LoadMetadata()
+	if err != nil {
+		return nil, nil, err
+	}
+	if opts.s2Source != "" {
+		err = tile.PairWithS2(opts.s2Source)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if applyFilters {
+		excluded, err := opts.filters.Exclude(tile)
+		if err != nil {
+			return nil, nil, err
+		}
+		if excluded {
+			return nil, nil, nil
+		}
+	}
+
+	labels := opts.labelMap.Apply(opts.nomenclature.Convert(tile.Metadata.Labels))
+	if len(labels) == 0 {
+		return nil, nil, nil
+	}
+	if opts.singleOnly && len(labels) != 1 {
+		return nil, nil, nil
+	}
+
+	if opts.firstOnly {
+		labels = labels[0:1]
+	}
+
+	return tile, labels, nil
+}
+
+// captured is the outcome of copying a capture.
+type captured struct {
+	skippedIndices []string
+}
+
+// processCapture copies a capture to the folders of its labels, returning
+// nil if the capture was skipped.
+func processCapture(item *process.Item, opts *options, applyFilters bool) (*captured, error) {
+	tile, labels, err := loadCapture(item, opts, applyFilters)
+	if err != nil || tile == nil {
+		return nil, err
+	}
+
+	err = copyCapture(item.Path(), opts.destination, labels)
+	if err != nil {
+		return nil, err
+	}
+	if tile.Paired != nil {
+		err = copyCapture(tile.Paired.GetCompletePath(), opts.destination, labels)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := &captured{}
+	if len(opts.indices) > 0 {
+		result.skippedIndices, err = writeIndices(tile, opts.destination, labels, opts.indices)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
 func copyCapture(sourceFolder string, destinationRoot string, labels []string) error {
 	// metadata is captured in the json file
 	files, err := ioutil.ReadDir(sourceFolder)
@@ -157,3 +471,38 @@ func copyCapture(sourceFolder string, destinationRoot string, labels []string) e
 
 	return nil
 }
+
+// writeIndices writes the index images of a tile, returning the names of the
+// indices skipped as the tile is missing their input bands.
+func writeIndices(tile *model.Tile, destinationRoot string, labels []string, indices []*model.SpectralIndex) ([]string, error) {
+	if len(tile.Images) == 0 {
+		err := tile.LoadFiles()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// index images are written next to the copied bands of the tile
+	skipped := make([]string, 0)
+	for _, index := range indices {
+		// tiles without the input bands, such as Sentinel-1 tiles, are skipped for the index
+		if len(tile.MissingBands(index.Bands)) > 0 {
+			skipped = append(skipped, index.Name)
+			continue
+		}
+		img, err := tile.ComputeIndex(index)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, label := range labels {
+			labelCleaned := labelRegex.ReplaceAllString(label, "_")
+			err = tile.WriteDerivedBand(path.Join(destinationRoot, labelCleaned), img)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return skipped, nil
+}
@@ -3,15 +3,13 @@ package main
 import (
 	"encoding/csv"
 	"io"
-	"io/ioutil"
-	"math/rand"
 	"os"
 	"path"
 	"runtime"
-	"strconv"
-	"strings"
 
+	"github.com/phorne-uncharted/bigearth-processor/filter"
 	"github.com/phorne-uncharted/bigearth-processor/model"
+	"github.com/phorne-uncharted/bigearth-processor/process"
 	"github.com/pkg/errors"
 	log "github.com/unchartedsoftware/plog"
 	"github.com/urfave/cli"
@@ -22,6 +20,25 @@ type labelCount struct {
 	count int
 }
 
+type options struct {
+	source       string
+	destination  string
+	logFrequency int
+	labelData    map[string]string
+	bandMapping  map[int]string
+	sampler      *process.Sampler
+	count        int
+	split        bool
+	expression   *model.Expression
+	nomenclature *model.Nomenclature
+	labelMap     *model.LabelMap
+	filters      *filter.Set
+	fileList     string
+	batchSize    int
+	workers      int
+	maxInFlight  int
+}
+
 func main() {
 
 	runtime.GOMAXPROCS(runtime.NumCPU())
@@ -52,6 +69,21 @@ func main() {
 			Value: 0.0001,
 			Usage: "The sample value from 0 to 1",
 		},
+		cli.Int64Flag{
+			Name:  "seed",
+			Value: 0,
+			Usage: "The seed used to sample, with 0 using a different sample every run",
+		},
+		cli.StringFlag{
+			Name:  "sample-mode",
+			Value: "",
+			Usage: "Either hash to always select the same tiles for a seed and sample value, or random, defaulting to hash when a seed is set",
+		},
+		cli.IntFlag{
+			Name:  "count",
+			Value: 0,
+			Usage: "The exact number of tiles to sample, used instead of the sample value",
+		},
 		cli.StringFlag{
 			Name:  "label-data",
 			Value: "",
@@ -71,7 +103,48 @@ func main() {
 			Name:  "split",
 			Usage: "If true, multiband image will be split. Otherwise it will be copied.",
 		},
+		cli.StringFlag{
+			Name:  "expression",
+			Value: "",
+			Usage: "Band math expression, such as (B08-B04)/(B08+B04) or B11/B12 > 1.2, written as an extra band when splitting",
+		},
+		cli.StringFlag{
+			Name:  "expression-name",
+			Value: "EXPR",
+			Usage: "The band name of the expression output",
+		},
+		cli.StringFlag{
+			Name:  "nomenclature",
+			Value: model.NomenclatureOriginalName,
+			Usage: "The label nomenclature, either original (43 CORINE labels) or bigearthnet-19",
+		},
+		cli.StringFlag{
+			Name:  "label-map",
+			Value: "",
+			Usage: "CSV or JSON file mapping old labels to new labels after applying the nomenclature, with an empty new label dropping the label",
+		},
+		cli.StringFlag{
+			Name:  "file-list",
+			Value: "",
+			Usage: "Text file listing the entries to process one per line, used instead of reading the source folder",
+		},
+		cli.IntFlag{
+			Name:  "batch-size",
+			Value: process.DefaultBatchSize,
+			Usage: "The number of source folder entries read at once",
+		},
+		cli.IntFlag{
+			Name:  "workers",
+			Value: runtime.NumCPU(),
+			Usage: "The number of tiles processed concurrently",
+		},
+		cli.IntFlag{
+			Name:  "max-in-flight",
+			Value: 0,
+			Usage: "The maximum number of tiles being processed at once, defaulting to 4 per worker",
+		},
 	}
+	app.Flags = append(app.Flags, filter.Flags()...)
 	app.Action = func(c *cli.Context) error {
 		if c.String("source") == "" {
 			return cli.NewExitError("missing commandline flag `--source`", 1)
@@ -81,27 +154,87 @@ func main() {
 		}
 
 		source := c.String("source")
-		destination := c.String("destination")
-		labelData := c.String("label-data")
-		logFrequency := c.Int("log-frequency")
-		bandsToDrop := c.String("drop-bands")
-		bandMappingRaw := c.String("band-mapping")
-		sample := c.Float64("sample")
 		split := c.Bool("split")
 
-		labels, err := loadLabels(labelData)
+		labels, err := loadLabels(c.String("label-data"))
 		if err != nil {
 			log.Errorf("%v", err)
 			return cli.NewExitError(errors.Cause(err), 2)
 		}
 
-		bandMapping, err := createBandMapping(bandsToDrop, bandMappingRaw)
+		bandMapping, err := createBandMapping(c.String("drop-bands"), c.String("band-mapping"))
 		if err != nil {
 			log.Errorf("%v", err)
 			return cli.NewExitError(errors.Cause(err), 2)
 		}
 
-		err = processFolder(source, destination, logFrequency, labels, bandMapping, sample, split)
+		var expression *model.Expression
+		if c.String("expression") != "" {
+			if !split {
+				return cli.NewExitError("commandline flag `--expression` requires `--split`", 1)
+			}
+			expression, err = parseExpression(source, c.String("file-list"), c.String("expression-name"), c.String("expression"), bandMapping)
+			if err != nil {
+				log.Errorf("%v", err)
+				return cli.NewExitError(errors.Cause(err), 1)
+			}
+		}
+
+		nomenclature, err := model.LookupNomenclature(c.String("nomenclature"))
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		labelMap, err := model.LoadLabelMap(c.String("label-map"))
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		for _, entry := range labelMap.Entries() {
+			log.Infof("mapping label '%s' to '%s'", entry[0], entry[1])
+		}
+
+		sampler, err := process.NewSampler(c.Float64("sample"), c.Int64("seed"), c.String("sample-mode"))
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		if sampler.Mode == process.SampleModeRandom && sampler.Seed != 0 {
+			log.Warnf("random sampling with a seed depends on the worker count and directory order, use hash sampling to reproduce samples")
+		}
+
+		// label queries use the labels of the label data rather than the CORINE labels
+		var knownLabels []string
+		seen := make(map[string]bool)
+		for _, label := range labels {
+			if !seen[label] {
+				seen[label] = true
+				knownLabels = append(knownLabels, label)
+			}
+		}
+
+		filters, err := filter.FromContext(c, knownLabels)
+		if err != nil {
+			log.Errorf("%v", err)
+			return cli.NewExitError(errors.Cause(err), 1)
+		}
+
+		err = processFolder(&options{
+			source:       source,
+			destination:  c.String("destination"),
+			logFrequency: c.Int("log-frequency"),
+			labelData:    labels,
+			bandMapping:  bandMapping,
+			sampler:      sampler,
+			count:        c.Int("count"),
+			split:        split,
+			expression:   expression,
+			nomenclature: nomenclature,
+			labelMap:     labelMap,
+			filters:      filters,
+			fileList:     c.String("file-list"),
+			batchSize:    c.Int("batch-size"),
+			workers:      c.Int("workers"),
+			maxInFlight:  c.Int("max-in-flight"),
+		})
 		if err != nil {
 			log.Errorf("%v", err)
 			return cli.NewExitError(errors.Cause(err), 2)
@@ -113,42 +246,145 @@ func main() {
 	app.Run(os.Args)
 }
 
-func processFolder(inputFolder string, outputFolder string, logFrequency int,
-	labelData map[string]string, bandMapping map[int]string, sample float64, split bool) error {
-	log.Infof("splitting tiles found in '%s', outputting resulting split images to '%s' (log frequency = %d, sample = %f, split = %v)", inputFolder, outputFolder, logFrequency, sample, split)
-	tileFiles, err := ioutil.ReadDir(inputFolder)
+func processFolder(opts *options) error {
+	log.Infof("splitting tiles found in '%s', outputting resulting split images to '%s' (log frequency = %d, sample = %f, split = %v)",
+		opts.source, opts.destination, opts.logFrequency, opts.sampler.Rate, opts.split)
+	source, err := process.NewSource(opts.source, opts.fileList, opts.batchSize)
 	if err != nil {
-		return errors.Wrapf(err, "unable to read contents of '%s'", inputFolder)
+		return err
+	}
+	defer source.Close()
+
+	os.MkdirAll(opts.destination, os.ModePerm)
+	if !opts.labelMap.Empty() {
+		err := opts.labelMap.Write(path.Join(opts.destination, "label_map.json"))
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Infof("sampling in %s mode with seed %d", opts.sampler.Mode, opts.sampler.Seed)
+	engine := process.NewEngine(opts.workers, opts.maxInFlight, false)
+	log.Infof("splitting tiles using %d workers", engine.Workers)
+
+	if opts.count > 0 {
+		source, err = selectTiles(engine, source, opts)
+		if err != nil {
+			return err
+		}
 	}
-	log.Infof("read %d tile images", len(tileFiles))
 
 	count := 0
-	for _, tileFile := range tileFiles {
+	err = engine.Run(source, func(item *process.Item) (interface{}, error) {
+		if opts.count == 0 && !opts.sampler.Select(item) {
+			return nil, nil
+		}
+		// tiles selected by count have already been filtered
+		return nil, processTile(item, opts, opts.count == 0)
+	}, func(item *process.Item, result interface{}) error {
 		count++
-		if count%logFrequency == 0 {
+		if count%opts.logFrequency == 0 {
 			log.Infof("processed %d tiles", count)
 		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
 
-		if rand.Float64() >= sample {
-			continue
+	log.Infof("%s", opts.filters.Summary())
+	log.Infof("done splitting tiles")
+
+	return nil
+}
+
+// selectTiles picks exactly count tiles out of those kept by the filters,
+// returning a source listing the selected tiles.
+func selectTiles(engine *process.Engine, source process.Source, opts *options) (process.Source, error) {
+	log.Infof("selecting %d tiles", opts.count)
+	reservoir := process.NewReservoir(opts.count, opts.sampler)
+	// aggregate in source order so a seeded selection does not depend on timing
+	ordered := process.NewEngine(engine.Workers, engine.MaxInFlight, true)
+	err := ordered.Run(source, func(item *process.Item) (interface{}, error) {
+		tile, _, err := loadTile(item, opts, true)
+		return tile != nil, err
+	}, func(item *process.Item, result interface{}) error {
+		if result.(bool) {
+			reservoir.Offer(item, nil)
 		}
-		if split {
-			tile := model.NewTileMultiBand(path.Join(inputFolder, tileFile.Name()))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
-			err = tile.SplitMultiBand(outputFolder, labelData[tileFile.Name()], bandMapping)
-		} else {
-			outputFilename := path.Join(outputFolder, labelData[tileFile.Name()], tileFile.Name())
-			err = copy(path.Join(inputFolder, tileFile.Name()), outputFilename)
+	selected := reservoir.Selected()
+	if len(selected) < opts.count {
+		log.Warnf("only %d tiles available to select %d from", len(selected), opts.count)
+	}
+	log.Infof("selected %d of %d tiles", len(selected), reservoir.Offered)
+
+	items := make([]*process.Item, len(selected))
+	for i, s := range selected {
+		items[i] = s.Item
+	}
+
+	return process.NewItemSource(items), nil
+}
+
+// loadTile creates the tile and determines its label, returning a nil tile
+// if the tile is excluded.
+func loadTile(item *process.Item, opts *options, applyFilters bool) (*model.Tile, string, error) {
+	tile := model.NewTileMultiBand(item.Path())
+	tile.BandMapping = opts.bandMapping
+
+	// the label data stands in for the metadata labels of the tile
+	label := opts.labelData[item.Name]
+	if label != "" {
+		tile.Metadata = &model.TileMetadata{
+			Labels: []string{label},
 		}
+	}
 
+	if applyFilters {
+		excluded, err := opts.filters.Exclude(tile)
 		if err != nil {
-			return err
+			return nil, "", err
+		}
+		if excluded {
+			return nil, "", nil
 		}
 	}
 
-	log.Infof("done splitting tiles")
+	// tiles whose label has no class in the nomenclature or is mapped to nothing are dropped
+	if label != "" {
+		classes := opts.labelMap.Apply(opts.nomenclature.Convert([]string{label}))
+		if len(classes) == 0 {
+			return nil, "", nil
+		}
+		label = classes[0]
+	}
 
-	return nil
+	return tile, label, nil
+}
+
+func processTile(item *process.Item, opts *options, applyFilters bool) error {
+	tile, label, err := loadTile(item, opts, applyFilters)
+	if err != nil || tile == nil {
+		return err
+	}
+
+	if opts.split {
+		err = tile.SplitMultiBand(opts.destination, label, opts.bandMapping)
+		if err == nil && opts.expression != nil {
+			err = writeExpression(tile, opts.destination, label, opts.bandMapping, opts.expression)
+		}
+	} else {
+		outputFilename := path.Join(opts.destination, label, item.Name)
+		err = copy(item.Path(), outputFilename)
+	}
+
+	return err
 }
 
 func loadLabels(labelFilename string) (map[string]string, error) {
@@ -205,36 +441,58 @@ func loadLabels(labelFilename string) (map[string]string, error) {
 }
 
 func createBandMapping(bandsToDrop string, bandMappingRaw string) (map[int]string, error) {
-	bandMapping := make(map[int]string)
+	bandMapping, err := model.ParseBandMapping(bandsToDrop, bandMappingRaw)
+	if err != nil {
+		return nil, err
+	}
 
-	// bandMappingRaw is a comma separated list of old:new values
-	mappingsRaw := strings.Split(bandMappingRaw, ",")
-	for _, mr := range mappingsRaw {
-		mapping := strings.Split(mr, ":")
-		old, err := strconv.Atoi(mapping[0])
-		if err != nil {
-			return nil, errors.Wrapf(err, "unable to parse source band integer for mapping")
-		}
+	// log mapping
+	for o, n := range bandMapping {
+		log.Infof("mapping band %d to %s", o, n)
+	}
 
-		bandMapping[old] = mapping[1]
+	return bandMapping, nil
+}
+
+// parseExpression parses the expression against the bands of the first
+// multiband image in the input folder.
+func parseExpression(inputFolder string, fileList string, name string, source string, bandMapping map[int]string) (*model.Expression, error) {
+	tileSource, err := process.NewSource(inputFolder, fileList, 1)
+	if err != nil {
+		return nil, err
 	}
+	defer tileSource.Close()
 
-	// bandsToDrop is a comma separated list of bands
-	for _, b := range strings.Split(bandsToDrop, ",") {
-		parsed, err := strconv.Atoi(b)
-		if err != nil {
-			return nil, errors.Wrapf(err, "unable to parse source band integer to drop")
-		}
+	item, err := tileSource.Next()
+	if err == io.EOF {
+		return nil, errors.Errorf("no tiles found in '%s'", inputFolder)
+	} else if err != nil {
+		return nil, err
+	}
 
-		bandMapping[parsed] = ""
+	tile := model.NewTileMultiBand(item.Path())
+	tile.BandMapping = bandMapping
+	err = tile.LoadImages()
+	if err != nil {
+		return nil, err
 	}
 
-	// log mapping
-	for o, n := range bandMapping {
-		log.Infof("mapping band %d to %s", o, n)
+	return model.ParseExpression(name, source, tile.BandNames())
+}
+
+func writeExpression(tile *model.Tile, outputFolder string, label string, bandMapping map[int]string, expression *model.Expression) error {
+	tile.BandMapping = bandMapping
+	err := tile.LoadImages()
+	if err != nil {
+		return err
 	}
 
-	return bandMapping, nil
+	img, err := tile.EvaluateExpression(expression)
+	if err != nil {
+		return err
+	}
+
+	return tile.WriteDerivedBand(tile.SplitFolder(outputFolder, label), img)
 }
 
 func copy(sourceFile string, destinationFile string) error {
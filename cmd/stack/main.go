@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+
+	"github.com/phorne-uncharted/bigearth-processor/model"
+	"github.com/phorne-uncharted/bigearth-processor/process"
+	"github.com/pkg/errors"
+	log "github.com/unchartedsoftware/plog"
+	"github.com/urfave/cli"
+)
+
+func main() {
+
+	runtime.GOMAXPROCS(runtime.NumCPU())
+
+	app := cli.NewApp()
+	app.Name = "bigearth-stacker"
+	app.Version = "0.1.0"
+	app.Usage = "Stack the single band images of big earth captures into multiband geotiffs"
+	app.UsageText = "bigearth-stacker --bands=<bands> --source=<filepath> --destination=<filepath>"
+	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "source",
+			Value: "",
+			Usage: "The folder containing all big earth captures",
+		},
+		cli.StringFlag{
+			Name:  "destination",
+			Value: "",
+			Usage: "The folder to write the stacked geotiffs",
+		},
+		cli.StringFlag{
+			Name:  "bands",
+			Value: strings.Join(model.BigEarthS2Bands, ","),
+			Usage: "CSV list of bands in the order they are to be stacked, using band names (B8A), aliases (NIR) or filename keys (8a)",
+		},
+		cli.IntFlag{
+			Name:  "log-frequency",
+			Value: 1000,
+			Usage: "Output log every X tiles",
+		},
+		cli.StringFlag{
+			Name:  "file-list",
+			Value: "",
+			Usage: "Text file listing the entries to process one per line, used instead of reading the source folder",
+		},
+		cli.IntFlag{
+			Name:  "batch-size",
+			Value: process.DefaultBatchSize,
+			Usage: "The number of source folder entries read at once",
+		},
+	}
+	app.Action = func(c *cli.Context) error {
+		if c.String("source") == "" {
+			return cli.NewExitError("missing commandline flag `--source`", 1)
+		}
+		if c.String("destination") == "" {
+			return cli.NewExitError("missing commandline flag `--destination`", 1)
+		}
+
+		source := c.String("source")
+		destination := c.String("destination")
+		logFrequency := c.Int("log-frequency")
+		bands := []string{}
+		if c.String("bands") != "" {
+			bands = strings.Split(c.String("bands"), ",")
+		}
+		for _, band := range bands {
+			if _, ok := model.LookupBand(band); !ok {
+				return cli.NewExitError(fmt.Sprintf("unknown band '%s'", band), 1)
+			}
+		}
+
+		err := processFolder(source, c.String("file-list"), c.Int("batch-size"), destination, bands, logFrequency)
+		if err != nil {
+			log.Errorf("%v", err)
+			return cli.NewExitError(errors.Cause(err), 2)
+		}
+
+		return nil
+	}
+	// run app
+	app.Run(os.Args)
+}
+
+func processFolder(folder string, fileList string, batchSize int, destinationRoot string, bands []string, logFrequency int) error {
+	os.MkdirAll(destinationRoot, os.ModePerm)
+
+	log.Infof("stacking captures found in '%s' using bands %v, outputting to '%s'", folder, bands, destinationRoot)
+	source, err := process.NewSource(folder, fileList, batchSize)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	count := 0
+	for {
+		capture, err := source.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if !capture.IsDir() {
+			continue
+		}
+
+		tile := model.NewTile(capture.Folder, capture.Name)
+		err = tile.LoadFiles()
+		if err != nil {
+			return err
+		}
+
+		missing := tile.MissingBands(bands)
+		if len(missing) > 0 {
+			log.Warnf("skipping capture '%s' missing bands %v", capture.Name, missing)
+			continue
+		}
+
+		outputFilename := path.Join(destinationRoot, capture.Name+".tif")
+		err = tile.StackBands(outputFilename, bands)
+		if err != nil {
+			return err
+		}
+
+		count++
+		if count%logFrequency == 0 {
+			log.Infof("stacked %d captures", count)
+		}
+	}
+
+	log.Infof("done stacking %d captures", count)
+
+	return nil
+}
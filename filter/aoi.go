@@ -0,0 +1,314 @@
+package filter
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/phorne-uncharted/bigearth-processor/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ReasonOutsideAOI is reported for tiles that do not overlap the area of
+	// interest.
+	ReasonOutsideAOI = "outside area of interest"
+	// ReasonUnknownFootprint is reported for tiles without a known location.
+	ReasonUnknownFootprint = "unknown footprint"
+)
+
+// AOIOptions configures the area of interest filters of a filter set. The
+// bounding box is in the format minLon,minLat,maxLon,maxLat and the area of
+// interest is a GeoJSON file of polygons in longitude and latitude.
+type AOIOptions struct {
+	BBox string
+	AOI  string
+}
+
+// point is a position as longitude and latitude.
+type point [2]float64
+
+// polygon is a list of rings, with the first ring being the exterior and the
+// others being holes.
+type polygon [][]point
+
+// AOIFilter excludes tiles whose footprint does not overlap any polygon of
+// the area of interest.
+type AOIFilter struct {
+	Name     string
+	Polygons []polygon
+	Bounds   *model.BoundingBox
+}
+
+type geoJSON struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Geometry    *geoJSON        `json:"geometry"`
+	Geometries  []*geoJSON      `json:"geometries"`
+	Features    []*geoJSON      `json:"features"`
+}
+
+// AddAOI adds the configured area of interest filters to the set.
+func (s *Set) AddAOI(options *AOIOptions) error {
+	if options.BBox != "" {
+		bbox, err := NewBBoxFilter(options.BBox)
+		if err != nil {
+			return err
+		}
+		s.Add(bbox)
+	}
+	if options.AOI != "" {
+		aoi, err := LoadAOIFilter(options.AOI)
+		if err != nil {
+			return err
+		}
+		s.Add(aoi)
+	}
+
+	return nil
+}
+
+// NewBBoxFilter creates a filter from a bounding box in the format
+// minLon,minLat,maxLon,maxLat.
+func NewBBoxFilter(bbox string) (*AOIFilter, error) {
+	fields := strings.Split(bbox, ",")
+	if len(fields) != 4 {
+		return nil, errors.Errorf("bounding box '%s' not in the format minLon,minLat,maxLon,maxLat", bbox)
+	}
+	values := make([]float64, 4)
+	for i, field := range fields {
+		value, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to parse bounding box '%s'", bbox)
+		}
+		values[i] = value
+	}
+	minLon, minLat, maxLon, maxLat := values[0], values[1], values[2], values[3]
+	if minLon >= maxLon || minLat >= maxLat {
+		return nil, errors.Errorf("bounding box '%s' has a minimum that is not below its maximum", bbox)
+	}
+
+	ring := []point{{minLon, minLat}, {maxLon, minLat}, {maxLon, maxLat}, {minLon, maxLat}, {minLon, minLat}}
+
+	return newAOIFilter(bbox, []polygon{{ring}})
+}
+
+// LoadAOIFilter creates a filter from the polygons and multipolygons of a
+// GeoJSON geometry, feature or feature collection.
+func LoadAOIFilter(filename string) (*AOIFilter, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read area of interest from '%s'", filename)
+	}
+
+	var root geoJSON
+	err = json.Unmarshal(data, &root)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to unmarshal area of interest from '%s'", filename)
+	}
+
+	polygons, err := root.polygons()
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse area of interest from '%s'", filename)
+	}
+
+	return newAOIFilter(filename, polygons)
+}
+
+func newAOIFilter(name string, polygons []polygon) (*AOIFilter, error) {
+	filter := &AOIFilter{
+		Name:     name,
+		Polygons: make([]polygon, 0),
+	}
+	for _, p := range polygons {
+		if len(p) == 0 || len(p[0]) < 3 {
+			continue
+		}
+		filter.Polygons = append(filter.Polygons, p)
+		for _, pt := range p[0] {
+			if filter.Bounds == nil {
+				filter.Bounds = &model.BoundingBox{ULX: pt[0], ULY: pt[1], LRX: pt[0], LRY: pt[1]}
+			}
+			filter.Bounds.ULX = math.Min(filter.Bounds.ULX, pt[0])
+			filter.Bounds.LRX = math.Max(filter.Bounds.LRX, pt[0])
+			filter.Bounds.ULY = math.Max(filter.Bounds.ULY, pt[1])
+			filter.Bounds.LRY = math.Min(filter.Bounds.LRY, pt[1])
+		}
+	}
+	if len(filter.Polygons) == 0 {
+		return nil, errors.Errorf("area of interest '%s' has no polygons", name)
+	}
+
+	return filter, nil
+}
+
+// Exclude excludes the tile if its footprint does not overlap the area of
+// interest.
+func (a *AOIFilter) Exclude(tile *model.Tile) (string, error) {
+	footprint, err := tile.GeographicFootprint()
+	if err != nil {
+		return "", err
+	}
+	if footprint == nil {
+		return ReasonUnknownFootprint, nil
+	}
+	if !a.Overlaps(footprint) {
+		return ReasonOutsideAOI, nil
+	}
+
+	return "", nil
+}
+
+// Overlaps returns true if the box, in longitude and latitude, overlaps any
+// polygon of the area of interest.
+func (a *AOIFilter) Overlaps(box *model.BoundingBox) bool {
+	if !boxesOverlap(a.Bounds, box) {
+		return false
+	}
+	for _, p := range a.Polygons {
+		if p.overlaps(box) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (g *geoJSON) polygons() ([]polygon, error) {
+	switch g.Type {
+	case "FeatureCollection":
+		polygons := make([]polygon, 0)
+		for _, f := range g.Features {
+			p, err := f.polygons()
+			if err != nil {
+				return nil, err
+			}
+			polygons = append(polygons, p...)
+		}
+		return polygons, nil
+	case "Feature":
+		if g.Geometry == nil {
+			return nil, nil
+		}
+		return g.Geometry.polygons()
+	case "GeometryCollection":
+		polygons := make([]polygon, 0)
+		for _, geometry := range g.Geometries {
+			p, err := geometry.polygons()
+			if err != nil {
+				return nil, err
+			}
+			polygons = append(polygons, p...)
+		}
+		return polygons, nil
+	case "Polygon":
+		var coordinates [][][]float64
+		err := json.Unmarshal(g.Coordinates, &coordinates)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to parse polygon coordinates")
+		}
+		p, err := toPolygon(coordinates)
+		if err != nil {
+			return nil, err
+		}
+		return []polygon{p}, nil
+	case "MultiPolygon":
+		var coordinates [][][][]float64
+		err := json.Unmarshal(g.Coordinates, &coordinates)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to parse multipolygon coordinates")
+		}
+		polygons := make([]polygon, len(coordinates))
+		for i, c := range coordinates {
+			polygons[i], err = toPolygon(c)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return polygons, nil
+	}
+
+	return nil, errors.Errorf("unsupported geometry type '%s' (expected polygons)", g.Type)
+}
+
+func toPolygon(coordinates [][][]float64) (polygon, error) {
+	p := make(polygon, len(coordinates))
+	for i, ring := range coordinates {
+		p[i] = make([]point, len(ring))
+		for j, position := range ring {
+			if len(position) < 2 {
+				return nil, errors.Errorf("polygon position has %d values", len(position))
+			}
+			p[i][j] = point{position[0], position[1]}
+		}
+	}
+
+	return p, nil
+}
+
+// overlaps returns true if the box and the polygon share any area, which is
+// when a corner of the box is in the polygon, a vertex of the polygon is in
+// the box or their edges cross.
+func (p polygon) overlaps(box *model.BoundingBox) bool {
+	corners := []point{{box.ULX, box.ULY}, {box.LRX, box.ULY}, {box.LRX, box.LRY}, {box.ULX, box.LRY}}
+	for _, c := range corners {
+		if p.contains(c) {
+			return true
+		}
+	}
+
+	for _, ring := range p {
+		for i, v := range ring {
+			if v[0] >= box.ULX && v[0] <= box.LRX && v[1] >= box.LRY && v[1] <= box.ULY {
+				return true
+			}
+			next := ring[(i+1)%len(ring)]
+			for c := range corners {
+				if segmentsCross(v, next, corners[c], corners[(c+1)%len(corners)]) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// contains tests if the point is in the polygon using the even-odd rule, so
+// points in holes are outside the polygon.
+func (p polygon) contains(pt point) bool {
+	inside := false
+	for _, ring := range p {
+		for i := range ring {
+			a := ring[i]
+			b := ring[(i+1)%len(ring)]
+			if (a[1] > pt[1]) != (b[1] > pt[1]) {
+				x := a[0] + (pt[1]-a[1])/(b[1]-a[1])*(b[0]-a[0])
+				if pt[0] < x {
+					inside = !inside
+				}
+			}
+		}
+	}
+
+	return inside
+}
+
+func segmentsCross(a point, b point, c point, d point) bool {
+	d1 := orientation(c, d, a)
+	d2 := orientation(c, d, b)
+	d3 := orientation(a, b, c)
+	d4 := orientation(a, b, d)
+
+	return ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0))
+}
+
+func orientation(a point, b point, c point) float64 {
+	return (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+}
+
+func boxesOverlap(a *model.BoundingBox, b *model.BoundingBox) bool {
+	return a.ULX <= b.LRX && b.ULX <= a.LRX && a.LRY <= b.ULY && b.LRY <= a.ULY
+}
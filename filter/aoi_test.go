@@ -0,0 +1,246 @@
+package filter
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/phorne-uncharted/bigearth-processor/model"
+)
+
+// box creates a bounding box from its minimum and maximum longitude and
+// latitude.
+func box(minLon float64, minLat float64, maxLon float64, maxLat float64) *model.BoundingBox {
+	return &model.BoundingBox{ULX: minLon, ULY: maxLat, LRX: maxLon, LRY: minLat}
+}
+
+// writeGeoJSON writes the GeoJSON to a file in a temporary folder, returning
+// the path of the file and a function removing the folder.
+func writeGeoJSON(t *testing.T, geoJSON string) (string, func()) {
+	t.Helper()
+	folder, err := ioutil.TempDir("", "aoi")
+	if err != nil {
+		t.Fatalf("unable to create temporary folder: %v", err)
+	}
+	filename := path.Join(folder, "aoi.geojson")
+	err = ioutil.WriteFile(filename, []byte(geoJSON), 0644)
+	if err != nil {
+		os.RemoveAll(folder)
+		t.Fatalf("unable to write geojson: %v", err)
+	}
+
+	return filename, func() { os.RemoveAll(folder) }
+}
+
+// loadAOI loads the area of interest from the GeoJSON.
+func loadAOI(t *testing.T, geoJSON string) *AOIFilter {
+	t.Helper()
+	filename, remove := writeGeoJSON(t, geoJSON)
+	defer remove()
+
+	aoi, err := LoadAOIFilter(filename)
+	if err != nil {
+		t.Fatalf("LoadAOIFilter failed: %v", err)
+	}
+
+	return aoi
+}
+
+// holedSquare is a square from 0 to 10 with a hole from 4 to 6.
+const holedSquare = `{"type": "Polygon", "coordinates": [
+	[[0, 0], [10, 0], [10, 10], [0, 10], [0, 0]],
+	[[4, 4], [6, 4], [6, 6], [4, 6], [4, 4]]
+]}`
+
+func TestBBoxFilterOverlaps(t *testing.T) {
+	aoi, err := NewBBoxFilter("5, 45, 10, 50")
+	if err != nil {
+		t.Fatalf("NewBBoxFilter failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		box  *model.BoundingBox
+		want bool
+	}{
+		{"inside", box(6, 46, 7, 47), true},
+		{"outside", box(11, 46, 12, 47), false},
+		{"outside diagonally", box(0, 40, 4, 44), false},
+		{"straddling an edge", box(9, 46, 11, 47), true},
+		{"straddling a corner", box(9, 49, 11, 51), true},
+		{"containing the box", box(0, 40, 20, 60), true},
+	}
+
+	for _, test := range tests {
+		if got := aoi.Overlaps(test.box); got != test.want {
+			t.Errorf("box %s overlaps is %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestPolygonOverlaps(t *testing.T) {
+	holed := loadAOI(t, holedSquare)
+	// a thin diagonal strip whose vertices and corners lie outside the boxes
+	strip := loadAOI(t, `{"type": "Polygon", "coordinates": [
+		[[-10, -11], [-9, -11], [11, 9], [10, 9], [-10, -11]]
+	]}`)
+
+	tests := []struct {
+		name string
+		aoi  *AOIFilter
+		box  *model.BoundingBox
+		want bool
+	}{
+		{"inside", holed, box(1, 1, 2, 2), true},
+		{"outside", holed, box(11, 11, 12, 12), false},
+		{"straddling the exterior", holed, box(-1, 2, 1, 3), true},
+		{"inside the hole", holed, box(4.5, 4.5, 5.5, 5.5), false},
+		{"straddling the hole", holed, box(5, 5, 7, 5.5), true},
+		{"containing the hole", holed, box(3, 3, 7, 7), true},
+		{"crossed by the edges", strip, box(-0.2, -5, 0.2, 5), true},
+		{"beside the strip", strip, box(-5, 2, -4, 3), false},
+	}
+
+	for _, test := range tests {
+		if got := test.aoi.Overlaps(test.box); got != test.want {
+			t.Errorf("box %s overlaps is %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestPolygonContains(t *testing.T) {
+	holed := loadAOI(t, holedSquare).Polygons[0]
+
+	tests := []struct {
+		pt   point
+		want bool
+	}{
+		{point{1, 1}, true},
+		{point{9, 5}, true},
+		{point{5, 5}, false},
+		{point{-1, 5}, false},
+		{point{5, 11}, false},
+	}
+
+	for _, test := range tests {
+		if got := holed.contains(test.pt); got != test.want {
+			t.Errorf("point %v contained is %v, want %v", test.pt, got, test.want)
+		}
+	}
+}
+
+func TestSegmentsCross(t *testing.T) {
+	tests := []struct {
+		a, b, c, d point
+		want       bool
+	}{
+		{point{0, 0}, point{2, 2}, point{0, 2}, point{2, 0}, true},
+		{point{0, 0}, point{1, 1}, point{2, 0}, point{3, 1}, false},
+		{point{0, 0}, point{2, 0}, point{0, 1}, point{2, 1}, false},
+		{point{0, 0}, point{1, 0}, point{2, -1}, point{2, 1}, false},
+	}
+
+	for _, test := range tests {
+		if got := segmentsCross(test.a, test.b, test.c, test.d); got != test.want {
+			t.Errorf("segments %v-%v and %v-%v cross is %v, want %v", test.a, test.b, test.c, test.d, got, test.want)
+		}
+	}
+}
+
+func TestLoadAOIFilterGeometries(t *testing.T) {
+	tests := []struct {
+		name     string
+		geoJSON  string
+		polygons int
+		bounds   *model.BoundingBox
+	}{
+		{
+			name:     "polygon with a hole",
+			geoJSON:  holedSquare,
+			polygons: 1,
+			bounds:   box(0, 0, 10, 10),
+		},
+		{
+			name: "multipolygon",
+			geoJSON: `{"type": "MultiPolygon", "coordinates": [
+				[[[0, 0], [1, 0], [1, 1], [0, 0]]],
+				[[[5, 5], [6, 5], [6, 7], [5, 5]]]
+			]}`,
+			polygons: 2,
+			bounds:   box(0, 0, 6, 7),
+		},
+		{
+			name: "feature collection",
+			geoJSON: `{"type": "FeatureCollection", "features": [
+				{"type": "Feature", "properties": {}, "geometry": {"type": "Polygon", "coordinates": [[[-3, -2], [-1, -2], [-1, 0], [-3, -2]]]}},
+				{"type": "Feature", "properties": {}, "geometry": null},
+				{"type": "Feature", "properties": {}, "geometry": {"type": "MultiPolygon", "coordinates": [
+					[[[2, 2], [4, 2], [4, 4], [2, 2]]],
+					[[[8, 1], [9, 1], [9, 3], [8, 1]]]
+				]}}
+			]}`,
+			polygons: 3,
+			bounds:   box(-3, -2, 9, 4),
+		},
+	}
+
+	for _, test := range tests {
+		aoi := loadAOI(t, test.geoJSON)
+		if len(aoi.Polygons) != test.polygons {
+			t.Errorf("%s parsed %d polygons, want %d", test.name, len(aoi.Polygons), test.polygons)
+		}
+		if *aoi.Bounds != *test.bounds {
+			t.Errorf("%s has bounds %+v, want %+v", test.name, *aoi.Bounds, *test.bounds)
+		}
+	}
+
+	// the multipolygon parts are matched separately
+	aoi := loadAOI(t, tests[1].geoJSON)
+	if !aoi.Overlaps(box(5.5, 5.5, 5.8, 5.8)) || aoi.Overlaps(box(2, 2, 3, 3)) {
+		t.Errorf("multipolygon overlaps the space between its parts")
+	}
+}
+
+func TestLoadAOIFilterErrors(t *testing.T) {
+	tests := []string{
+		`{"type": "LineString", "coordinates": [[0, 0], [1, 1]]}`,
+		`{"type": "Polygon", "coordinates": [[[0], [1, 0], [1, 1], [0]]]}`,
+		`{"type": "Polygon", "coordinates": [[[0, 0], [1, 1]]]}`,
+		`{"type": "FeatureCollection", "features": []}`,
+		`{"type": "Polygon", "coordinates": `,
+	}
+
+	for _, geoJSON := range tests {
+		filename, remove := writeGeoJSON(t, geoJSON)
+		_, err := LoadAOIFilter(filename)
+		remove()
+		if err == nil {
+			t.Errorf("LoadAOIFilter of %s did not fail", geoJSON)
+		}
+	}
+
+	_, err := LoadAOIFilter("missing.geojson")
+	if err == nil {
+		t.Errorf("LoadAOIFilter of a missing file did not fail")
+	}
+}
+
+func TestNewBBoxFilterErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"5,45,10",
+		"5,45,10,50,60",
+		"5,north,10,50",
+		"10,45,5,50",
+		"5,50,10,45",
+		"5,45,5,50",
+	}
+
+	for _, bbox := range tests {
+		_, err := NewBBoxFilter(bbox)
+		if err == nil {
+			t.Errorf("NewBBoxFilter(%s) did not fail", bbox)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package filter
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/phorne-uncharted/bigearth-processor/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ReasonSeasonalSnow is reported for tiles covered by seasonal snow.
+	ReasonSeasonalSnow = "seasonal snow"
+	// ReasonCloudShadow is reported for tiles covered by clouds or cloud shadow.
+	ReasonCloudShadow = "cloud and shadow"
+
+	snowExpression   = "(B03 - B11) / (B03 + B11) > 0.4 and B08 > 0.11"
+	cloudExpression  = "B02 > 0.25 and B04 > 0.25 and B08 > 0.3 and (B03 - B11) / (B03 + B11) < 0.4"
+	shadowExpression = "B08 < 0.08 and B11 < 0.08 and (B03 - B08) / (B03 + B08) < 0"
+)
+
+// ExclusionOptions configures the snow and cloud exclusions of a filter set.
+// Lists are the CSV files of patch names published with BigEarth, while
+// detection uses band thresholds to find tiles where more than the maximum
+// fraction of pixels are snow, cloud or shadow.
+type ExclusionOptions struct {
+	SnowList         string
+	CloudList        string
+	DetectSnow       bool
+	DetectClouds     bool
+	MaxCoverFraction float64
+}
+
+// ExclusionList excludes the tiles named in a list.
+type ExclusionList struct {
+	Reason string
+	tiles  map[string]bool
+}
+
+// HeuristicFilter excludes tiles where the fraction of pixels matching a band
+// math mask exceeds a maximum.
+type HeuristicFilter struct {
+	Reason      string
+	Expressions []*model.Expression
+	MaxFraction float64
+}
+
+// AddExclusions adds the configured exclusion lists and heuristics to the set.
+func (s *Set) AddExclusions(options *ExclusionOptions) error {
+	if options.SnowList != "" {
+		list, err := LoadExclusionList(options.SnowList, ReasonSeasonalSnow)
+		if err != nil {
+			return err
+		}
+		s.Add(list)
+	}
+	if options.CloudList != "" {
+		list, err := LoadExclusionList(options.CloudList, ReasonCloudShadow)
+		if err != nil {
+			return err
+		}
+		s.Add(list)
+	}
+	if options.DetectSnow {
+		heuristic, err := NewHeuristicFilter(ReasonSeasonalSnow+" (detected)", options.MaxCoverFraction, snowExpression)
+		if err != nil {
+			return err
+		}
+		s.Add(heuristic)
+	}
+	if options.DetectClouds {
+		heuristic, err := NewHeuristicFilter(ReasonCloudShadow+" (detected)", options.MaxCoverFraction, cloudExpression, shadowExpression)
+		if err != nil {
+			return err
+		}
+		s.Add(heuristic)
+	}
+
+	return nil
+}
+
+// LoadExclusionList reads the tile names to exclude from the first column
+// of a CSV file.
+func LoadExclusionList(filename string, reason string) (*ExclusionList, error) {
+	csvFile, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open exclusion list '%s'", filename)
+	}
+	defer csvFile.Close()
+	reader := csv.NewReader(csvFile)
+	reader.FieldsPerRecord = -1
+
+	list := &ExclusionList{
+		Reason: reason,
+		tiles:  make(map[string]bool),
+	}
+	for {
+		line, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "failed to read exclusion list '%s'", filename)
+		}
+		if len(line) > 0 && line[0] != "" {
+			list.tiles[strings.TrimSpace(line[0])] = true
+		}
+	}
+
+	return list, nil
+}
+
+// Exclude excludes the tile if it, or its corresponding Sentinel-2 patch, is
+// in the list.
+func (l *ExclusionList) Exclude(tile *model.Tile) (string, error) {
+	names := []string{tile.TileName, strings.TrimSuffix(tile.TileName, path.Ext(tile.TileName))}
+	if tile.Metadata != nil && tile.Metadata.CorrespondingS2Patch != "" {
+		names = append(names, tile.Metadata.CorrespondingS2Patch)
+	}
+
+	for _, name := range names {
+		if l.tiles[name] {
+			return l.Reason, nil
+		}
+	}
+
+	return "", nil
+}
+
+// NewHeuristicFilter creates a filter excluding tiles where more than the
+// maximum fraction of pixels match any of the mask expressions.
+func NewHeuristicFilter(reason string, maxFraction float64, expressions ...string) (*HeuristicFilter, error) {
+	heuristic := &HeuristicFilter{
+		Reason:      reason,
+		Expressions: make([]*model.Expression, len(expressions)),
+		MaxFraction: maxFraction,
+	}
+	for i, source := range expressions {
+		expression, err := model.ParseExpression(reason, source, nil)
+		if err != nil {
+			return nil, err
+		}
+		heuristic.Expressions[i] = expression
+	}
+
+	return heuristic, nil
+}
+
+// Exclude loads the bands of the tile and excludes it if the masked fraction
+// is above the maximum. Tiles missing bands used by the masks, such as
+// Sentinel-1 tiles, cannot be evaluated and are kept.
+func (h *HeuristicFilter) Exclude(tile *model.Tile) (string, error) {
+	if len(tile.Images) == 0 {
+		err := tile.LoadFiles()
+		if err != nil {
+			return "", err
+		}
+	}
+	for _, expression := range h.Expressions {
+		if len(tile.MissingBands(expression.Bands)) > 0 {
+			return "", nil
+		}
+	}
+
+	var covered []bool
+	for _, expression := range h.Expressions {
+		mask, err := tile.EvaluateExpression(expression)
+		if err != nil {
+			return "", err
+		}
+		if covered == nil {
+			covered = make([]bool, len(mask.PixelsUint8))
+		}
+		if len(mask.PixelsUint8) != len(covered) {
+			return "", errors.Errorf("mask sizes differ for tile '%s'", tile.TileName)
+		}
+		for p, v := range mask.PixelsUint8 {
+			covered[p] = covered[p] || v != 0
+		}
+	}
+
+	count := 0
+	for _, c := range covered {
+		if c {
+			count++
+		}
+	}
+	if len(covered) > 0 && float64(count)/float64(len(covered)) > h.MaxFraction {
+		return h.Reason, nil
+	}
+
+	return "", nil
+}
@@ -0,0 +1,78 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/phorne-uncharted/bigearth-processor/model"
+)
+
+// bandImage creates a 2x2 uint16 image of the band.
+func bandImage(band string, pixels ...uint16) *model.Image {
+	return &model.Image{
+		Band:         band,
+		SizeX:        2,
+		SizeY:        2,
+		GeoTransform: [6]float64{0, 10, 0, 0, 0, -10},
+		DataType:     model.DataTypeUint16,
+		PixelsUint16: pixels,
+	}
+}
+
+func TestHeuristicFilterExclude(t *testing.T) {
+	heuristic, err := NewHeuristicFilter(ReasonSeasonalSnow, 0.5, snowExpression)
+	if err != nil {
+		t.Fatalf("NewHeuristicFilter failed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		images []*model.Image
+		want   string
+	}{
+		{
+			name: "mostly snow",
+			images: []*model.Image{
+				bandImage("03", 8000, 8000, 8000, 1000),
+				bandImage("08", 3000, 3000, 3000, 3000),
+				bandImage("11", 1000, 1000, 1000, 1000),
+			},
+			want: ReasonSeasonalSnow,
+		},
+		{
+			name: "half snow",
+			images: []*model.Image{
+				bandImage("03", 8000, 8000, 1000, 1000),
+				bandImage("08", 3000, 3000, 3000, 3000),
+				bandImage("11", 1000, 1000, 1000, 1000),
+			},
+			want: "",
+		},
+		{
+			name: "sentinel-1",
+			images: []*model.Image{
+				bandImage("vv", 1, 2, 3, 4),
+				bandImage("vh", 1, 2, 3, 4),
+			},
+			want: "",
+		},
+		{
+			name: "missing a mask band",
+			images: []*model.Image{
+				bandImage("03", 8000, 8000, 8000, 8000),
+				bandImage("08", 3000, 3000, 3000, 3000),
+			},
+			want: "",
+		},
+	}
+
+	for _, test := range tests {
+		tile := &model.Tile{TileName: "S2A_MSIL2A_20170613T101031_0_45", Images: test.images}
+		reason, err := heuristic.Exclude(tile)
+		if err != nil {
+			t.Fatalf("%s failed: %v", test.name, err)
+		}
+		if reason != test.want {
+			t.Errorf("%s excluded for %q, want %q", test.name, reason, test.want)
+		}
+	}
+}
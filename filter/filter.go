@@ -0,0 +1,101 @@
+package filter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/phorne-uncharted/bigearth-processor/model"
+)
+
+// Filter decides if a tile should be excluded from processing.
+type Filter interface {
+	// Exclude returns the reason the tile is excluded, or "" if it is kept.
+	Exclude(tile *model.Tile) (string, error)
+}
+
+// Set applies a series of filters to tiles, tracking why tiles are excluded.
+// Tiles can be filtered concurrently once all filters have been added.
+type Set struct {
+	filters      []Filter
+	mutex        sync.Mutex
+	total        int
+	excluded     int
+	reasonCounts map[string]int
+}
+
+// NewSet creates an empty filter set that keeps every tile.
+func NewSet() *Set {
+	return &Set{
+		filters:      make([]Filter, 0),
+		reasonCounts: make(map[string]int),
+	}
+}
+
+// Add appends a filter to the set.
+func (s *Set) Add(f Filter) {
+	s.filters = append(s.filters, f)
+}
+
+// Empty returns true if the set has no filters.
+func (s *Set) Empty() bool {
+	return len(s.filters) == 0
+}
+
+// Exclude applies the filters in order and returns true if the tile is
+// excluded by any of them. Only the first reason is recorded.
+func (s *Set) Exclude(tile *model.Tile) (bool, error) {
+	reason := ""
+	for _, f := range s.filters {
+		r, err := f.Exclude(tile)
+		if err != nil {
+			return false, err
+		}
+		if r != "" {
+			reason = r
+			break
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.total++
+	if reason == "" {
+		return false, nil
+	}
+	s.excluded++
+	s.reasonCounts[reason]++
+
+	return true, nil
+}
+
+// Counts returns the number of tiles excluded for each reason.
+func (s *Set) Counts() map[string]int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	counts := make(map[string]int)
+	for r, c := range s.reasonCounts {
+		counts[r] = c
+	}
+
+	return counts
+}
+
+// Summary describes how many tiles were excluded and why.
+func (s *Set) Summary() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	reasons := make([]string, 0, len(s.reasonCounts))
+	for r := range s.reasonCounts {
+		reasons = append(reasons, r)
+	}
+	sort.Strings(reasons)
+
+	details := make([]string, len(reasons))
+	for i, r := range reasons {
+		details[i] = fmt.Sprintf("%s: %d", r, s.reasonCounts[r])
+	}
+
+	return fmt.Sprintf("excluded %d of %d tiles (%s)", s.excluded, s.total, strings.Join(details, ", "))
+}
@@ -0,0 +1,142 @@
+package filter
+
+import (
+	"github.com/urfave/cli"
+)
+
+// ExclusionFlags returns the command line flags configuring the snow and cloud
+// exclusions of a command, to be read back with ExclusionsFromContext.
+func ExclusionFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  "snow-list",
+			Value: "",
+			Usage: "CSV file listing the patches covered by seasonal snow to exclude",
+		},
+		cli.StringFlag{
+			Name:  "cloud-list",
+			Value: "",
+			Usage: "CSV file listing the patches covered by clouds or cloud shadow to exclude",
+		},
+		cli.BoolFlag{
+			Name:  "detect-snow",
+			Usage: "If true, exclude patches detected as snow covered using band thresholds",
+		},
+		cli.BoolFlag{
+			Name:  "detect-clouds",
+			Usage: "If true, exclude patches detected as cloud or shadow covered using band thresholds",
+		},
+		cli.Float64Flag{
+			Name:  "max-cover-fraction",
+			Value: 0.5,
+			Usage: "The fraction of detected snow, cloud or shadow pixels above which a patch is excluded",
+		},
+	}
+}
+
+// Flags returns the command line flags configuring all the filters of a
+// command, to be read back with FromContext.
+func Flags() []cli.Flag {
+	return append(ExclusionFlags(), []cli.Flag{
+		cli.StringFlag{
+			Name:  "from",
+			Value: "",
+			Usage: "Only keep tiles acquired on or after the date, in the format 2006-01-02",
+		},
+		cli.StringFlag{
+			Name:  "to",
+			Value: "",
+			Usage: "Only keep tiles acquired on or before the date, in the format 2006-01-02",
+		},
+		cli.StringFlag{
+			Name:  "months",
+			Value: "",
+			Usage: "CSV list of months (numbers or names) to keep tiles acquired in",
+		},
+		cli.StringFlag{
+			Name:  "seasons",
+			Value: "",
+			Usage: "CSV list of seasons (winter, spring, summer, autumn) to keep tiles acquired in",
+		},
+		cli.StringFlag{
+			Name:  "hemisphere",
+			Value: HemisphereAuto,
+			Usage: "The hemisphere determining the seasons, either north, south or auto to use the tile projection",
+		},
+		cli.StringFlag{
+			Name:  "satellites",
+			Value: "",
+			Usage: "CSV list of satellites (S2A, S2B, ...) to keep tiles captured by",
+		},
+		cli.StringFlag{
+			Name:  "bbox",
+			Value: "",
+			Usage: "Bounding box in the format minLon,minLat,maxLon,maxLat to keep tiles overlapping",
+		},
+		cli.StringFlag{
+			Name:  "aoi",
+			Value: "",
+			Usage: "GeoJSON file of polygons, in longitude and latitude, to keep tiles overlapping",
+		},
+		cli.StringFlag{
+			Name:  "label-query",
+			Value: "",
+			Usage: "Boolean query over the tile labels, such as '\"Pastures\" AND NOT labels >= 3', to keep matching tiles",
+		},
+	}...)
+}
+
+// ExclusionsFromContext creates the filter set configured by the flags of
+// ExclusionFlags.
+func ExclusionsFromContext(c *cli.Context) (*Set, error) {
+	filters := NewSet()
+	err := filters.AddExclusions(&ExclusionOptions{
+		SnowList:         c.String("snow-list"),
+		CloudList:        c.String("cloud-list"),
+		DetectSnow:       c.Bool("detect-snow"),
+		DetectClouds:     c.Bool("detect-clouds"),
+		MaxCoverFraction: c.Float64("max-cover-fraction"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return filters, nil
+}
+
+// FromContext creates the filter set configured by the flags of Flags, with
+// the labels of the label query checked against the known labels if
+// specified.
+func FromContext(c *cli.Context, knownLabels []string) (*Set, error) {
+	filters, err := ExclusionsFromContext(c)
+	if err != nil {
+		return nil, err
+	}
+
+	err = filters.AddTemporal(&TemporalOptions{
+		From:       c.String("from"),
+		To:         c.String("to"),
+		Months:     c.String("months"),
+		Seasons:    c.String("seasons"),
+		Hemisphere: c.String("hemisphere"),
+		Satellites: c.String("satellites"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = filters.AddAOI(&AOIOptions{
+		BBox: c.String("bbox"),
+		AOI:  c.String("aoi"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = filters.AddLabelQuery(c.String("label-query"), knownLabels)
+	if err != nil {
+		return nil, err
+	}
+
+	return filters, nil
+}
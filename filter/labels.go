@@ -0,0 +1,58 @@
+package filter
+
+import (
+	"github.com/phorne-uncharted/bigearth-processor/model"
+)
+
+const (
+	// ReasonLabelQuery is reported for tiles whose labels do not match the
+	// label query.
+	ReasonLabelQuery = "label query not matched"
+	// ReasonUnknownLabels is reported for tiles without metadata labels.
+	ReasonUnknownLabels = "unknown labels"
+)
+
+// LabelQueryFilter excludes tiles whose metadata labels do not match a query.
+type LabelQueryFilter struct {
+	Query *model.LabelQuery
+}
+
+// AddLabelQuery adds a filter keeping the tiles matching the label query, with
+// the labels of the query checked against the known labels if specified.
+func (s *Set) AddLabelQuery(query string, knownLabels []string) error {
+	if query == "" {
+		return nil
+	}
+
+	filter, err := NewLabelQueryFilter(query, knownLabels)
+	if err != nil {
+		return err
+	}
+	s.Add(filter)
+
+	return nil
+}
+
+// NewLabelQueryFilter creates a filter from a label query.
+func NewLabelQueryFilter(query string, knownLabels []string) (*LabelQueryFilter, error) {
+	parsed, err := model.ParseLabelQuery(query, knownLabels)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LabelQueryFilter{
+		Query: parsed,
+	}, nil
+}
+
+// Exclude excludes the tile if its metadata labels do not match the query.
+func (l *LabelQueryFilter) Exclude(tile *model.Tile) (string, error) {
+	if tile.Metadata == nil {
+		return ReasonUnknownLabels, nil
+	}
+	if !l.Query.Match(tile.Metadata.Labels) {
+		return ReasonLabelQuery, nil
+	}
+
+	return "", nil
+}
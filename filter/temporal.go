@@ -0,0 +1,280 @@
+package filter
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/phorne-uncharted/bigearth-processor/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ReasonDateRange is reported for tiles acquired outside the date range.
+	ReasonDateRange = "outside date range"
+	// ReasonMonth is reported for tiles acquired in other months.
+	ReasonMonth = "other month"
+	// ReasonSeason is reported for tiles acquired in other seasons.
+	ReasonSeason = "other season"
+	// ReasonSatellite is reported for tiles captured by other satellites.
+	ReasonSatellite = "other satellite"
+	// ReasonUnknownDate is reported for tiles without an acquisition date.
+	ReasonUnknownDate = "unknown acquisition date"
+	// ReasonUnknownSatellite is reported for tiles without a satellite.
+	ReasonUnknownSatellite = "unknown satellite"
+
+	// HemisphereAuto determines the hemisphere from the tile projection,
+	// defaulting to the northern hemisphere.
+	HemisphereAuto = "auto"
+	// HemisphereNorth uses the northern hemisphere seasons.
+	HemisphereNorth = "north"
+	// HemisphereSouth uses the southern hemisphere seasons.
+	HemisphereSouth = "south"
+
+	filterDateFormat = "2006-01-02"
+)
+
+// TemporalOptions configures the acquisition date and satellite filters of a
+// filter set. Dates are inclusive and in the format 2006-01-02 while months,
+// seasons and satellites are CSV lists.
+type TemporalOptions struct {
+	From       string
+	To         string
+	Months     string
+	Seasons    string
+	Hemisphere string
+	Satellites string
+}
+
+// DateRangeFilter excludes tiles acquired outside a date range, with a zero
+// date leaving the range open.
+type DateRangeFilter struct {
+	From time.Time
+	To   time.Time
+}
+
+// MonthFilter excludes tiles acquired outside the months.
+type MonthFilter struct {
+	Months map[time.Month]bool
+}
+
+// SeasonFilter excludes tiles acquired outside the seasons.
+type SeasonFilter struct {
+	Seasons    map[string]bool
+	Hemisphere string
+}
+
+// SatelliteFilter excludes tiles captured by other satellites.
+type SatelliteFilter struct {
+	Satellites map[string]bool
+}
+
+// AddTemporal adds the configured acquisition date and satellite filters to
+// the set.
+func (s *Set) AddTemporal(options *TemporalOptions) error {
+	if options.From != "" || options.To != "" {
+		dateRange, err := NewDateRangeFilter(options.From, options.To)
+		if err != nil {
+			return err
+		}
+		s.Add(dateRange)
+	}
+	if options.Months != "" {
+		months, err := NewMonthFilter(options.Months)
+		if err != nil {
+			return err
+		}
+		s.Add(months)
+	}
+	if options.Seasons != "" {
+		seasons, err := NewSeasonFilter(options.Seasons, options.Hemisphere)
+		if err != nil {
+			return err
+		}
+		s.Add(seasons)
+	}
+	if options.Satellites != "" {
+		s.Add(NewSatelliteFilter(options.Satellites))
+	}
+
+	return nil
+}
+
+// NewDateRangeFilter creates a filter keeping tiles acquired from the start
+// of the first date to the end of the last date.
+func NewDateRangeFilter(from string, to string) (*DateRangeFilter, error) {
+	dateRange := &DateRangeFilter{}
+	var err error
+	if from != "" {
+		dateRange.From, err = time.Parse(filterDateFormat, from)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to parse from date '%s'", from)
+		}
+	}
+	if to != "" {
+		dateRange.To, err = time.Parse(filterDateFormat, to)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to parse to date '%s'", to)
+		}
+		dateRange.To = dateRange.To.AddDate(0, 0, 1)
+	}
+	if !dateRange.From.IsZero() && !dateRange.To.IsZero() && !dateRange.From.Before(dateRange.To) {
+		return nil, errors.Errorf("from date '%s' is after to date '%s'", from, to)
+	}
+
+	return dateRange, nil
+}
+
+// Exclude excludes the tile if it was acquired outside the range.
+func (d *DateRangeFilter) Exclude(tile *model.Tile) (string, error) {
+	date, ok := tile.AcquisitionDate()
+	if !ok {
+		return ReasonUnknownDate, nil
+	}
+	if !d.From.IsZero() && date.Before(d.From) {
+		return ReasonDateRange, nil
+	}
+	if !d.To.IsZero() && !date.Before(d.To) {
+		return ReasonDateRange, nil
+	}
+
+	return "", nil
+}
+
+// NewMonthFilter creates a filter from a CSV list of month numbers or names.
+func NewMonthFilter(months string) (*MonthFilter, error) {
+	filter := &MonthFilter{
+		Months: make(map[time.Month]bool),
+	}
+	for _, m := range strings.Split(months, ",") {
+		m = strings.TrimSpace(m)
+		if m == "" {
+			continue
+		}
+		month, err := parseMonth(m)
+		if err != nil {
+			return nil, err
+		}
+		filter.Months[month] = true
+	}
+
+	return filter, nil
+}
+
+// Exclude excludes the tile if it was acquired in another month.
+func (m *MonthFilter) Exclude(tile *model.Tile) (string, error) {
+	date, ok := tile.AcquisitionDate()
+	if !ok {
+		return ReasonUnknownDate, nil
+	}
+	if !m.Months[date.Month()] {
+		return ReasonMonth, nil
+	}
+
+	return "", nil
+}
+
+// NewSeasonFilter creates a filter from a CSV list of seasons, using the
+// seasons of the hemisphere.
+func NewSeasonFilter(seasons string, hemisphere string) (*SeasonFilter, error) {
+	hemisphere = strings.ToLower(hemisphere)
+	if hemisphere == "" {
+		hemisphere = HemisphereAuto
+	}
+	if hemisphere != HemisphereAuto && hemisphere != HemisphereNorth && hemisphere != HemisphereSouth {
+		return nil, errors.Errorf("unknown hemisphere '%s' (expected %s, %s or %s)", hemisphere, HemisphereAuto, HemisphereNorth, HemisphereSouth)
+	}
+
+	filter := &SeasonFilter{
+		Seasons:    make(map[string]bool),
+		Hemisphere: hemisphere,
+	}
+	for _, s := range strings.Split(seasons, ",") {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		season, err := model.ParseSeason(s)
+		if err != nil {
+			return nil, err
+		}
+		filter.Seasons[season] = true
+	}
+
+	return filter, nil
+}
+
+// Exclude excludes the tile if it was acquired in another season.
+func (s *SeasonFilter) Exclude(tile *model.Tile) (string, error) {
+	season, ok := TileSeason(tile, s.Hemisphere)
+	if !ok {
+		return ReasonUnknownDate, nil
+	}
+	if !s.Seasons[season] {
+		return ReasonSeason, nil
+	}
+
+	return "", nil
+}
+
+// TileSeason returns the season the tile was acquired in for the hemisphere,
+// which is determined from the tile projection in auto mode.
+func TileSeason(tile *model.Tile, hemisphere string) (string, bool) {
+	date, ok := tile.AcquisitionDate()
+	if !ok {
+		return "", false
+	}
+
+	southern := hemisphere == HemisphereSouth
+	if hemisphere == HemisphereAuto || hemisphere == "" {
+		southern, _ = tile.Southern()
+	}
+
+	return model.SeasonOf(date, southern), true
+}
+
+// NewSatelliteFilter creates a filter from a CSV list of satellites.
+func NewSatelliteFilter(satellites string) *SatelliteFilter {
+	filter := &SatelliteFilter{
+		Satellites: make(map[string]bool),
+	}
+	for _, s := range strings.Split(satellites, ",") {
+		s = strings.ToUpper(strings.TrimSpace(s))
+		if s != "" {
+			filter.Satellites[s] = true
+		}
+	}
+
+	return filter
+}
+
+// Exclude excludes the tile if it was captured by another satellite.
+func (s *SatelliteFilter) Exclude(tile *model.Tile) (string, error) {
+	satellite, ok := tile.Satellite()
+	if !ok {
+		return ReasonUnknownSatellite, nil
+	}
+	if !s.Satellites[satellite] {
+		return ReasonSatellite, nil
+	}
+
+	return "", nil
+}
+
+func parseMonth(month string) (time.Month, error) {
+	number, err := strconv.Atoi(month)
+	if err == nil {
+		if number < 1 || number > 12 {
+			return 0, errors.Errorf("month %d is not between 1 and 12", number)
+		}
+		return time.Month(number), nil
+	}
+
+	for m := time.January; m <= time.December; m++ {
+		name := strings.ToLower(m.String())
+		if strings.EqualFold(month, name) || strings.EqualFold(month, name[:3]) {
+			return m, nil
+		}
+	}
+
+	return 0, errors.Errorf("unknown month '%s'", month)
+}
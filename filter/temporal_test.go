@@ -0,0 +1,190 @@
+package filter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/phorne-uncharted/bigearth-processor/model"
+)
+
+// datedTile creates a tile acquired on the date, with the projection in its
+// metadata.
+func datedTile(name string, date time.Time, projection string) *model.Tile {
+	tile := model.NewTile("source", name)
+	tile.Metadata = &model.TileMetadata{
+		AcquisitionDate: date,
+		Projection:      projection,
+	}
+
+	return tile
+}
+
+func day(year int, month time.Month, d int, hour int) time.Time {
+	return time.Date(year, month, d, hour, 0, 0, 0, time.UTC)
+}
+
+// excludeReasons returns the reason each tile is excluded for.
+func excludeReasons(t *testing.T, filter Filter, tiles []*model.Tile) []string {
+	t.Helper()
+	reasons := make([]string, len(tiles))
+	for i, tile := range tiles {
+		reason, err := filter.Exclude(tile)
+		if err != nil {
+			t.Fatalf("Exclude failed for %s: %v", tile.TileName, err)
+		}
+		reasons[i] = reason
+	}
+
+	return reasons
+}
+
+func TestDateRangeFilter(t *testing.T) {
+	tiles := []*model.Tile{
+		datedTile("patch_0_0", day(2017, 5, 31, 23), ""),
+		datedTile("patch_0_1", day(2017, 6, 1, 0), ""),
+		datedTile("patch_0_2", day(2017, 8, 31, 23), ""),
+		datedTile("patch_0_3", day(2017, 9, 1, 0), ""),
+		model.NewTile("source", "S2A_MSIL2A_20170613T101031_45_62"),
+		model.NewTile("source", "patch_0_4"),
+	}
+
+	tests := []struct {
+		from string
+		to   string
+		want []string
+	}{
+		{"2017-06-01", "2017-08-31", []string{ReasonDateRange, "", "", ReasonDateRange, "", ReasonUnknownDate}},
+		{"2017-06-01", "", []string{ReasonDateRange, "", "", "", "", ReasonUnknownDate}},
+		{"", "2017-06-01", []string{"", "", ReasonDateRange, ReasonDateRange, ReasonDateRange, ReasonUnknownDate}},
+		{"2017-06-01", "2017-06-01", []string{ReasonDateRange, "", ReasonDateRange, ReasonDateRange, ReasonDateRange, ReasonUnknownDate}},
+	}
+
+	for _, test := range tests {
+		filter, err := NewDateRangeFilter(test.from, test.to)
+		if err != nil {
+			t.Fatalf("NewDateRangeFilter(%s, %s) failed: %v", test.from, test.to, err)
+		}
+		got := excludeReasons(t, filter, tiles)
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("range %s to %s excludes %s for '%s', want '%s'", test.from, test.to, tiles[i].TileName, got[i], test.want[i])
+			}
+		}
+	}
+}
+
+func TestNewDateRangeFilterErrors(t *testing.T) {
+	tests := [][2]string{
+		{"2017-06-31", ""},
+		{"", "06/01/2017"},
+		{"2017-06-02", "2017-06-01"},
+	}
+
+	for _, test := range tests {
+		_, err := NewDateRangeFilter(test[0], test[1])
+		if err == nil {
+			t.Errorf("NewDateRangeFilter(%s, %s) did not fail", test[0], test[1])
+		}
+	}
+}
+
+func TestMonthFilter(t *testing.T) {
+	tiles := []*model.Tile{
+		datedTile("patch_0_0", day(2017, 1, 10, 0), ""),
+		datedTile("patch_0_1", day(2018, 6, 10, 0), ""),
+		datedTile("patch_0_2", day(2017, 12, 10, 0), ""),
+		model.NewTile("source", "patch_0_3"),
+	}
+
+	tests := []struct {
+		months string
+		want   []string
+	}{
+		{"1,6", []string{"", "", ReasonMonth, ReasonUnknownDate}},
+		{"jan, December", []string{"", ReasonMonth, "", ReasonUnknownDate}},
+		{"JUNE,", []string{ReasonMonth, "", ReasonMonth, ReasonUnknownDate}},
+	}
+
+	for _, test := range tests {
+		filter, err := NewMonthFilter(test.months)
+		if err != nil {
+			t.Fatalf("NewMonthFilter(%s) failed: %v", test.months, err)
+		}
+		got := excludeReasons(t, filter, tiles)
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("months %s exclude %s for '%s', want '%s'", test.months, tiles[i].TileName, got[i], test.want[i])
+			}
+		}
+	}
+
+	for _, months := range []string{"0", "13", "jun,smarch"} {
+		_, err := NewMonthFilter(months)
+		if err == nil {
+			t.Errorf("NewMonthFilter(%s) did not fail", months)
+		}
+	}
+}
+
+func TestSeasonFilter(t *testing.T) {
+	north := `PROJCS["WGS 84 / UTM zone 33N",AUTHORITY["EPSG","32633"]]`
+	south := `PROJCS["WGS 84 / UTM zone 35S",AUTHORITY["EPSG","32735"]]`
+	tiles := []*model.Tile{
+		datedTile("patch_0_0", day(2017, 7, 1, 0), north),
+		datedTile("patch_0_1", day(2017, 7, 1, 0), south),
+		datedTile("patch_0_2", day(2017, 1, 1, 0), south),
+		datedTile("patch_0_3", day(2017, 7, 1, 0), ""),
+		model.NewTile("source", "patch_0_4"),
+	}
+
+	tests := []struct {
+		seasons    string
+		hemisphere string
+		want       []string
+	}{
+		{"summer", "", []string{"", ReasonSeason, "", "", ReasonUnknownDate}},
+		{"summer", HemisphereNorth, []string{"", "", ReasonSeason, "", ReasonUnknownDate}},
+		{"summer", HemisphereSouth, []string{ReasonSeason, ReasonSeason, "", ReasonSeason, ReasonUnknownDate}},
+		{"winter,fall", "AUTO", []string{ReasonSeason, "", ReasonSeason, ReasonSeason, ReasonUnknownDate}},
+	}
+
+	for _, test := range tests {
+		filter, err := NewSeasonFilter(test.seasons, test.hemisphere)
+		if err != nil {
+			t.Fatalf("NewSeasonFilter(%s, %s) failed: %v", test.seasons, test.hemisphere, err)
+		}
+		got := excludeReasons(t, filter, tiles)
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("seasons %s (%s) exclude %s for '%s', want '%s'", test.seasons, test.hemisphere, tiles[i].TileName, got[i], test.want[i])
+			}
+		}
+	}
+
+	for _, test := range [][2]string{{"summer", "east"}, {"summer,dry", HemisphereNorth}} {
+		_, err := NewSeasonFilter(test[0], test[1])
+		if err == nil {
+			t.Errorf("NewSeasonFilter(%s, %s) did not fail", test[0], test[1])
+		}
+	}
+}
+
+func TestSatelliteFilter(t *testing.T) {
+	s1 := model.NewTile("source", "patch_0_2")
+	s1.Metadata = &model.TileMetadata{TileSource: "S1A_IW_GRDH_1SDV_20170613T165043"}
+	tiles := []*model.Tile{
+		model.NewTile("source", "S2A_MSIL2A_20170613T101031_45_62"),
+		model.NewTile("source", "S2B_MSIL2A_20170613T101031_45_62"),
+		s1,
+		model.NewTile("source", "patch_0_3"),
+	}
+
+	filter := NewSatelliteFilter(" s2a, S1A ,")
+	want := []string{"", ReasonSatellite, "", ReasonUnknownSatellite}
+	got := excludeReasons(t, filter, tiles)
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("satellites exclude %s for '%s', want '%s'", tiles[i].TileName, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package model
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	// SensorSentinel1 is the C-band SAR sensor of the Sentinel-1 satellites.
+	SensorSentinel1 = "Sentinel-1"
+	// SensorSentinel2 is the multispectral sensor of the Sentinel-2 satellites.
+	SensorSentinel2 = "Sentinel-2"
+)
+
+var (
+	// BigEarthS2Bands are the Sentinel-2 bands included in every BigEarth patch.
+	BigEarthS2Bands = []string{"B01", "B02", "B03", "B04", "B05", "B06", "B07", "B08", "B8A", "B09", "B11", "B12"}
+
+	// BigEarthS1Bands are the Sentinel-1 bands included in every BigEarth-MM patch.
+	BigEarthS1Bands = []string{"VV", "VH"}
+
+	bandRegistry = []*BandInfo{
+		{Name: "B01", Key: "01", Sensor: SensorSentinel2, Wavelength: 442.7, Bandwidth: 21, Resolution: 60, Aliases: []string{"coastal", "aerosol"}},
+		{Name: "B02", Key: "02", Sensor: SensorSentinel2, Wavelength: 492.4, Bandwidth: 66, Resolution: 10, Aliases: []string{"blue"}},
+		{Name: "B03", Key: "03", Sensor: SensorSentinel2, Wavelength: 559.8, Bandwidth: 36, Resolution: 10, Aliases: []string{"green"}},
+		{Name: "B04", Key: "04", Sensor: SensorSentinel2, Wavelength: 664.6, Bandwidth: 31, Resolution: 10, Aliases: []string{"red"}},
+		{Name: "B05", Key: "05", Sensor: SensorSentinel2, Wavelength: 704.1, Bandwidth: 15, Resolution: 20, Aliases: []string{"re1", "rededge1"}},
+		{Name: "B06", Key: "06", Sensor: SensorSentinel2, Wavelength: 740.5, Bandwidth: 15, Resolution: 20, Aliases: []string{"re2", "rededge2"}},
+		{Name: "B07", Key: "07", Sensor: SensorSentinel2, Wavelength: 782.8, Bandwidth: 20, Resolution: 20, Aliases: []string{"re3", "rededge3"}},
+		{Name: "B08", Key: "08", Sensor: SensorSentinel2, Wavelength: 832.8, Bandwidth: 106, Resolution: 10, Aliases: []string{"nir"}},
+		{Name: "B8A", Key: "8a", Sensor: SensorSentinel2, Wavelength: 864.7, Bandwidth: 21, Resolution: 20, Aliases: []string{"nir2", "narrownir", "re4", "rededge4"}},
+		{Name: "B09", Key: "09", Sensor: SensorSentinel2, Wavelength: 945.1, Bandwidth: 20, Resolution: 60, Aliases: []string{"watervapour", "wv"}},
+		{Name: "B10", Key: "10", Sensor: SensorSentinel2, Wavelength: 1373.5, Bandwidth: 31, Resolution: 60, Aliases: []string{"cirrus"}},
+		{Name: "B11", Key: "11", Sensor: SensorSentinel2, Wavelength: 1613.7, Bandwidth: 91, Resolution: 20, Aliases: []string{"swir1"}},
+		{Name: "B12", Key: "12", Sensor: SensorSentinel2, Wavelength: 2202.4, Bandwidth: 175, Resolution: 20, Aliases: []string{"swir2"}},
+		{Name: "VV", Key: "vv", Sensor: SensorSentinel1, Wavelength: 5.5465763e7, Resolution: 10},
+		{Name: "VH", Key: "vh", Sensor: SensorSentinel1, Wavelength: 5.5465763e7, Resolution: 10},
+	}
+
+	bandLookup = createBandLookup()
+)
+
+// BandInfo describes a band captured by one of the Sentinel sensors. The
+// wavelength and bandwidth are in nanometers and the resolution is the
+// native ground sampling distance in meters. The key is the band name as it
+// appears in BigEarth image filenames.
+type BandInfo struct {
+	Name       string
+	Key        string
+	Sensor     string
+	Wavelength float64
+	Bandwidth  float64
+	Resolution float64
+	Aliases    []string
+}
+
+func createBandLookup() map[string]*BandInfo {
+	lookup := make(map[string]*BandInfo)
+	for _, b := range bandRegistry {
+		lookup[strings.ToLower(b.Name)] = b
+		lookup[b.Key] = b
+		for _, alias := range b.Aliases {
+			lookup[alias] = b
+		}
+	}
+
+	return lookup
+}
+
+// LookupBand returns the band matching the name, which can be the canonical
+// name (B8A), the filename key (8a) or a common alias (nir2).
+func LookupBand(name string) (*BandInfo, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	band, ok := bandLookup[name]
+	if !ok {
+		band, ok = bandLookup[padBandKey(name)]
+	}
+
+	return band, ok
+}
+
+// ListBands returns the registered bands of a sensor ordered by wavelength.
+func ListBands(sensor string) []*BandInfo {
+	bands := make([]*BandInfo, 0)
+	for _, b := range bandRegistry {
+		if b.Sensor == sensor {
+			bands = append(bands, b)
+		}
+	}
+	sort.SliceStable(bands, func(i int, j int) bool {
+		return bands[i].Wavelength < bands[j].Wavelength
+	})
+
+	return bands
+}
+
+// BandInfo returns the registry entry for the band of the image.
+func (i *Image) BandInfo() (*BandInfo, bool) {
+	return LookupBand(i.Band)
+}
+
+// MissingBands returns the expected bands that are not found in the tile.
+func (t *Tile) MissingBands(expected []string) []string {
+	missing := make([]string, 0)
+	for _, band := range expected {
+		if t.GetImage(band) == nil {
+			missing = append(missing, band)
+		}
+	}
+
+	return missing
+}
+
+// normalizeBandName turns band names such as 'B8A', 'NIR2' or 'B2' into the
+// lower case form extracted from BigEarth filenames.
+func normalizeBandName(band string) string {
+	info, ok := LookupBand(band)
+	if ok {
+		return info.Key
+	}
+
+	return padBandKey(strings.ToLower(strings.TrimSpace(band)))
+}
+
+func padBandKey(band string) string {
+	if len(band) > 1 && band[0] == 'b' {
+		band = band[1:]
+	}
+	if len(band) == 1 {
+		band = "0" + band
+	}
+
+	return band
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ParseBandMapping builds the mapping of raster band index to band name from a
+// comma separated list of bands to drop and a comma separated list of
+// (old band):(new band) mappings. Dropped bands map to "". New band names are
+// resolved against the band registry, accepting aliases such as NIR, and
+// mapped to their canonical name. Spectral index names are also accepted for
+// images of derived bands.
+func ParseBandMapping(bandsToDrop string, bandMappingRaw string) (map[int]string, error) {
+	bandMapping := make(map[int]string)
+
+	// bandMappingRaw is a comma separated list of old:new values
+	if bandMappingRaw != "" {
+		for _, mr := range strings.Split(bandMappingRaw, ",") {
+			mapping := strings.Split(mr, ":")
+			if len(mapping) != 2 {
+				return nil, errors.Errorf("band mapping '%s' not in the format (old band):(new band)", mr)
+			}
+			old, err := strconv.Atoi(mapping[0])
+			if err != nil {
+				return nil, errors.Wrapf(err, "unable to parse source band integer for mapping")
+			}
+
+			band, err := canonicalBandName(mapping[1])
+			if err != nil {
+				return nil, err
+			}
+
+			bandMapping[old] = band
+		}
+	}
+
+	// bandsToDrop is a comma separated list of bands
+	if bandsToDrop != "" {
+		for _, b := range strings.Split(bandsToDrop, ",") {
+			parsed, err := strconv.Atoi(b)
+			if err != nil {
+				return nil, errors.Wrapf(err, "unable to parse source band integer to drop")
+			}
+
+			bandMapping[parsed] = ""
+		}
+	}
+
+	return bandMapping, nil
+}
+
+func canonicalBandName(name string) (string, error) {
+	info, ok := LookupBand(name)
+	if ok {
+		return info.Name, nil
+	}
+	index, ok := LookupIndex(name)
+	if ok {
+		return index.Name, nil
+	}
+
+	return "", errors.Errorf("unknown band '%s' in band mapping", name)
+}
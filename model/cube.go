@@ -0,0 +1,85 @@
+package model
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// DataCube is the bands of a tile aligned on a common grid, indexed as
+// [band][y][x]. Nodata pixels are NaN.
+type DataCube struct {
+	Bands        []string
+	SizeX        int
+	SizeY        int
+	GeoTransform [6]float64
+	Projection   string
+	Data         [][][]float32
+}
+
+// DataCube resamples the bands of the tile to the target resolution, in the
+// units of the tile projection, and returns them as an aligned cube. A
+// resolution of 0 uses the resolution of the finest band. Bands are ordered
+// as specified, or by band name if no order is given.
+func (t *Tile) DataCube(resolution float64, method ResampleMethod, bandOrder []string) (*DataCube, error) {
+	if len(t.Images) == 0 {
+		err := t.LoadFiles()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	images, err := t.selectBands(bandOrder)
+	if err != nil {
+		return nil, err
+	}
+	if len(images) == 0 {
+		return nil, errors.Errorf("no bands found in tile '%s'", t.TileName)
+	}
+
+	// the finest band determines the extent and the default resolution
+	reference := images[0]
+	for _, img := range images {
+		if img.SizeX*img.SizeY > reference.SizeX*reference.SizeY {
+			reference = img
+		}
+	}
+
+	sizeX := reference.SizeX
+	sizeY := reference.SizeY
+	if resolution > 0 {
+		sizeX = int(math.Round(float64(reference.SizeX) * math.Abs(reference.GeoTransform[1]) / resolution))
+		sizeY = int(math.Round(float64(reference.SizeY) * math.Abs(reference.GeoTransform[5]) / resolution))
+		if sizeX < 1 || sizeY < 1 {
+			return nil, errors.Errorf("resolution %f is coarser than the extent of tile '%s'", resolution, t.TileName)
+		}
+	}
+
+	cube := &DataCube{
+		Bands:        make([]string, len(images)),
+		SizeX:        sizeX,
+		SizeY:        sizeY,
+		GeoTransform: scaleGeoTransform(reference.GeoTransform, float64(reference.SizeX)/float64(sizeX), float64(reference.SizeY)/float64(sizeY)),
+		Projection:   reference.Projection,
+		Data:         make([][][]float32, len(images)),
+	}
+	for b, img := range images {
+		cube.Bands[b] = img.Band
+		cube.Data[b] = img.ResampleValues(sizeX, sizeY, method)
+	}
+
+	return cube, nil
+}
+
+// BandIndex returns the index of the band in the cube, or -1 if the cube does
+// not have that band.
+func (c *DataCube) BandIndex(band string) int {
+	band = normalizeBandName(band)
+	for b, name := range c.Bands {
+		if normalizeBandName(name) == band {
+			return b
+		}
+	}
+
+	return -1
+}
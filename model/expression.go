@@ -0,0 +1,575 @@
+package model
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	tokenEOF = iota
+	tokenNumber
+	tokenIdentifier
+	tokenOperator
+	tokenString
+)
+
+var (
+	expressionFunctions = map[string]struct {
+		args int
+		call func(args []float64) float64
+	}{
+		"abs":  {1, func(a []float64) float64 { return math.Abs(a[0]) }},
+		"sqrt": {1, func(a []float64) float64 { return math.Sqrt(a[0]) }},
+		"log":  {1, func(a []float64) float64 { return math.Log(a[0]) }},
+		"exp":  {1, func(a []float64) float64 { return math.Exp(a[0]) }},
+		"min":  {2, func(a []float64) float64 { return math.Min(a[0], a[1]) }},
+		"max":  {2, func(a []float64) float64 { return math.Max(a[0], a[1]) }},
+	}
+)
+
+// Expression is a band math formula evaluated per pixel over the bands of a
+// tile. Sentinel-2 bands are evaluated as reflectance. Expressions whose
+// result is a comparison or logical operation produce boolean masks.
+type Expression struct {
+	Name   string
+	Source string
+	Bands  []string
+	root   expressionNode
+}
+
+type expressionNode interface {
+	eval(values []float64) float64
+	boolean() bool
+}
+
+type numberNode struct {
+	value float64
+}
+
+type bandNode struct {
+	index int
+}
+
+type unaryNode struct {
+	op      string
+	operand expressionNode
+}
+
+type binaryNode struct {
+	op    string
+	left  expressionNode
+	right expressionNode
+}
+
+type callNode struct {
+	call func(args []float64) float64
+	args []expressionNode
+}
+
+type expressionToken struct {
+	kind     int
+	text     string
+	position int
+}
+
+type expressionParser struct {
+	tokens    []expressionToken
+	current   int
+	available []string
+	bands     []string
+	bandIndex map[string]int
+}
+
+// ParseExpression parses a band math expression. Identifiers are resolved
+// against the available bands, or against the band registry if no bands are
+// specified, so that references to missing bands are reported when parsing.
+func ParseExpression(name string, source string, availableBands []string) (*Expression, error) {
+	tokens, err := tokenizeExpression(source)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &expressionParser{
+		tokens:    tokens,
+		available: availableBands,
+		bands:     make([]string, 0),
+		bandIndex: make(map[string]int),
+	}
+	root, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if parser.peek().kind != tokenEOF {
+		return nil, parser.errorf("unexpected '%s'", parser.peek().text)
+	}
+
+	return &Expression{
+		Name:   name,
+		Source: source,
+		Bands:  parser.bands,
+		root:   root,
+	}, nil
+}
+
+// IsMask returns true if the expression produces a boolean mask.
+func (e *Expression) IsMask() bool {
+	return e.root.boolean()
+}
+
+// EvaluateExpression evaluates the expression for every pixel of the tile on
+// the grid of its finest input band. Masks are returned as uint8 images
+// holding 0 or 1 and other expressions as float32 images with NaN nodata.
+func (t *Tile) EvaluateExpression(expression *Expression) (*Image, error) {
+	if len(t.Images) == 0 {
+		err := t.LoadFiles()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	missing := t.MissingBands(expression.Bands)
+	if len(missing) > 0 {
+		return nil, errors.Errorf("tile '%s' is missing bands %v used by '%s'", t.TileName, missing, expression.Source)
+	}
+
+	cube, err := t.DataCube(0, ResampleBilinear, expression.Bands)
+	if err != nil {
+		return nil, err
+	}
+
+	scales := make([]float64, len(expression.Bands))
+	for b, band := range expression.Bands {
+		scales[b] = 1
+		info, ok := LookupBand(band)
+		if ok && info.Sensor == SensorSentinel2 {
+			scales[b] = s2ReflectanceScale
+		}
+	}
+
+	img := newDerivedImage(expression.Name, cube)
+	if expression.IsMask() {
+		img.DataType = DataTypeUint8
+		img.HasNoData = false
+		img.NoData = 0
+		img.PixelsFloat32 = nil
+		img.PixelsUint8 = make([]uint8, cube.SizeX*cube.SizeY)
+	}
+
+	values := make([]float64, len(expression.Bands))
+	for y := 0; y < cube.SizeY; y++ {
+		for x := 0; x < cube.SizeX; x++ {
+			for b := range values {
+				values[b] = float64(cube.Data[b][y][x]) / scales[b]
+			}
+			value := expression.root.eval(values)
+			p := y*cube.SizeX + x
+			if img.DataType == DataTypeUint8 {
+				if value != 0 {
+					img.PixelsUint8[p] = 1
+				}
+			} else {
+				if math.IsInf(value, 0) {
+					value = math.NaN()
+				}
+				img.PixelsFloat32[p] = float32(value)
+			}
+		}
+	}
+
+	return img, nil
+}
+
+// BandNames returns the names of the loaded bands of the tile.
+func (t *Tile) BandNames() []string {
+	bands := make([]string, len(t.Images))
+	for i, img := range t.Images {
+		bands[i] = img.Band
+	}
+
+	return bands
+}
+
+func (n *numberNode) eval(values []float64) float64 {
+	return n.value
+}
+
+func (n *numberNode) boolean() bool {
+	return false
+}
+
+func (n *bandNode) eval(values []float64) float64 {
+	return values[n.index]
+}
+
+func (n *bandNode) boolean() bool {
+	return false
+}
+
+func (n *unaryNode) eval(values []float64) float64 {
+	value := n.operand.eval(values)
+	if n.op == "!" {
+		return boolToFloat(value == 0)
+	}
+
+	return -value
+}
+
+func (n *unaryNode) boolean() bool {
+	return n.op == "!"
+}
+
+func (n *binaryNode) eval(values []float64) float64 {
+	left := n.left.eval(values)
+
+	// short circuit the logical operators
+	switch n.op {
+	case "&&":
+		return boolToFloat(left != 0 && n.right.eval(values) != 0)
+	case "||":
+		return boolToFloat(left != 0 || n.right.eval(values) != 0)
+	}
+
+	right := n.right.eval(values)
+	switch n.op {
+	case "+":
+		return left + right
+	case "-":
+		return left - right
+	case "*":
+		return left * right
+	case "/":
+		return left / right
+	case "^":
+		return math.Pow(left, right)
+	case ">":
+		return boolToFloat(left > right)
+	case ">=":
+		return boolToFloat(left >= right)
+	case "<":
+		return boolToFloat(left < right)
+	case "<=":
+		return boolToFloat(left <= right)
+	case "==":
+		return boolToFloat(left == right)
+	case "!=":
+		return boolToFloat(left != right)
+	}
+
+	return math.NaN()
+}
+
+func (n *binaryNode) boolean() bool {
+	switch n.op {
+	case "+", "-", "*", "/", "^":
+		return false
+	}
+
+	return true
+}
+
+func (n *callNode) eval(values []float64) float64 {
+	args := make([]float64, len(n.args))
+	for i, arg := range n.args {
+		args[i] = arg.eval(values)
+	}
+
+	return n.call(args)
+}
+
+func (n *callNode) boolean() bool {
+	return false
+}
+
+func boolToFloat(value bool) float64 {
+	if value {
+		return 1
+	}
+
+	return 0
+}
+
+func tokenizeExpression(source string) ([]expressionToken, error) {
+	tokens := make([]expressionToken, 0)
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := i
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case unicode.IsDigit(r) || r == '.':
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			// scientific notation
+			if i < len(runes) && (runes[i] == 'e' || runes[i] == 'E') {
+				i++
+				if i < len(runes) && (runes[i] == '+' || runes[i] == '-') {
+					i++
+				}
+				for i < len(runes) && unicode.IsDigit(runes[i]) {
+					i++
+				}
+			}
+			tokens = append(tokens, expressionToken{tokenNumber, string(runes[start:i]), start})
+		case unicode.IsLetter(r) || r == '_':
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			text := string(runes[start:i])
+			switch strings.ToLower(text) {
+			case "and":
+				tokens = append(tokens, expressionToken{tokenOperator, "&&", start})
+			case "or":
+				tokens = append(tokens, expressionToken{tokenOperator, "||", start})
+			case "not":
+				tokens = append(tokens, expressionToken{tokenOperator, "!", start})
+			default:
+				tokens = append(tokens, expressionToken{tokenIdentifier, text, start})
+			}
+		default:
+			op := ""
+			if i+1 < len(runes) {
+				switch string(runes[i : i+2]) {
+				case ">=", "<=", "==", "!=", "&&", "||":
+					op = string(runes[i : i+2])
+				}
+			}
+			if op == "" {
+				if !strings.ContainsRune("+-*/^()<>!,", r) {
+					return nil, errors.Errorf("unexpected character '%c' at position %d", r, start)
+				}
+				op = string(r)
+			}
+			i += len(op)
+			tokens = append(tokens, expressionToken{tokenOperator, op, start})
+		}
+	}
+	tokens = append(tokens, expressionToken{tokenEOF, "end of expression", len(runes)})
+
+	return tokens, nil
+}
+
+func (p *expressionParser) peek() expressionToken {
+	return p.tokens[p.current]
+}
+
+func (p *expressionParser) next() expressionToken {
+	token := p.tokens[p.current]
+	if token.kind != tokenEOF {
+		p.current++
+	}
+
+	return token
+}
+
+func (p *expressionParser) accept(ops ...string) (string, bool) {
+	token := p.peek()
+	if token.kind != tokenOperator {
+		return "", false
+	}
+	for _, op := range ops {
+		if token.text == op {
+			p.current++
+			return op, true
+		}
+	}
+
+	return "", false
+}
+
+func (p *expressionParser) errorf(format string, args ...interface{}) error {
+	return errors.Wrapf(errors.Errorf(format, args...), "invalid expression at position %d", p.peek().position)
+}
+
+func (p *expressionParser) parseOr() (expressionNode, error) {
+	return p.parseBinary(p.parseAnd, "||")
+}
+
+func (p *expressionParser) parseAnd() (expressionNode, error) {
+	return p.parseBinary(p.parseNot, "&&")
+}
+
+// parseNot parses logical negation, which binds looser than comparisons so
+// that not B11 > 1 negates the comparison.
+func (p *expressionParser) parseNot() (expressionNode, error) {
+	if _, ok := p.accept("!"); ok {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: "!", operand: operand}, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *expressionParser) parseComparison() (expressionNode, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	op, ok := p.accept(">", ">=", "<", "<=", "==", "!=")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	return &binaryNode{op: op, left: left, right: right}, nil
+}
+
+func (p *expressionParser) parseAdditive() (expressionNode, error) {
+	return p.parseBinary(p.parseMultiplicative, "+", "-")
+}
+
+func (p *expressionParser) parseMultiplicative() (expressionNode, error) {
+	return p.parseBinary(p.parseUnary, "*", "/")
+}
+
+func (p *expressionParser) parseBinary(operand func() (expressionNode, error), ops ...string) (expressionNode, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op, ok := p.accept(ops...)
+		if !ok {
+			return left, nil
+		}
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *expressionParser) parseUnary() (expressionNode, error) {
+	if _, ok := p.accept("-"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: "-", operand: operand}, nil
+	}
+
+	return p.parsePower()
+}
+
+func (p *expressionParser) parsePower() (expressionNode, error) {
+	base, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := p.accept("^"); ok {
+		exponent, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &binaryNode{op: "^", left: base, right: exponent}, nil
+	}
+
+	return base, nil
+}
+
+func (p *expressionParser) parsePrimary() (expressionNode, error) {
+	token := p.peek()
+	switch token.kind {
+	case tokenNumber:
+		p.next()
+		value, err := strconv.ParseFloat(token.text, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid number '%s' at position %d", token.text, token.position)
+		}
+		return &numberNode{value: value}, nil
+	case tokenIdentifier:
+		if _, ok := expressionFunctions[strings.ToLower(token.text)]; ok {
+			return p.parseCall()
+		}
+		p.next()
+		return p.resolveBand(token)
+	case tokenOperator:
+		if token.text == "(" {
+			p.next()
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := p.accept(")"); !ok {
+				return nil, p.errorf("expected ')' but found '%s'", p.peek().text)
+			}
+			return node, nil
+		}
+	}
+
+	return nil, p.errorf("unexpected '%s'", token.text)
+}
+
+func (p *expressionParser) parseCall() (expressionNode, error) {
+	token := p.next()
+	function := expressionFunctions[strings.ToLower(token.text)]
+	if _, ok := p.accept("("); !ok {
+		return nil, p.errorf("expected '(' after function '%s'", token.text)
+	}
+
+	args := make([]expressionNode, 0)
+	for {
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if _, ok := p.accept(","); !ok {
+			break
+		}
+	}
+	if _, ok := p.accept(")"); !ok {
+		return nil, p.errorf("expected ')' but found '%s'", p.peek().text)
+	}
+	if len(args) != function.args {
+		return nil, errors.Errorf("function '%s' at position %d expects %d arguments but got %d", token.text, token.position, function.args, len(args))
+	}
+
+	return &callNode{call: function.call, args: args}, nil
+}
+
+func (p *expressionParser) resolveBand(token expressionToken) (expressionNode, error) {
+	key := normalizeBandName(token.text)
+	if index, ok := p.bandIndex[key]; ok {
+		return &bandNode{index: index}, nil
+	}
+
+	found := false
+	if p.available == nil {
+		_, found = LookupBand(token.text)
+	} else {
+		for _, band := range p.available {
+			if normalizeBandName(band) == key {
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		if p.available == nil {
+			return nil, errors.Errorf("unknown band '%s' at position %d", token.text, token.position)
+		}
+		return nil, errors.Errorf("unknown band '%s' at position %d, available bands are %v", token.text, token.position, p.available)
+	}
+
+	p.bandIndex[key] = len(p.bands)
+	p.bands = append(p.bands, token.text)
+
+	return &bandNode{index: len(p.bands) - 1}, nil
+}
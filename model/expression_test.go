@@ -0,0 +1,138 @@
+package model
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+var testBands = []string{"B02", "B03", "B04", "B08", "B11"}
+
+// evalExpression evaluates an expression with the values of the bands by
+// canonical name.
+func evalExpression(t *testing.T, expression *Expression, values map[string]float64) float64 {
+	t.Helper()
+	ordered := make([]float64, len(expression.Bands))
+	for i, band := range expression.Bands {
+		info, ok := LookupBand(band)
+		if !ok {
+			t.Fatalf("band %s not registered", band)
+		}
+		value, ok := values[info.Name]
+		if !ok {
+			t.Fatalf("no test value for band %s", band)
+		}
+		ordered[i] = value
+	}
+
+	return expression.root.eval(ordered)
+}
+
+func TestParseExpressionEvaluates(t *testing.T) {
+	values := map[string]float64{"B02": 1, "B03": 2, "B04": 3, "B08": 5, "B11": 0.5}
+	tests := []struct {
+		source string
+		want   float64
+		mask   bool
+	}{
+		{"1 + 2 * 3", 7, false},
+		{"(1 + 2) * 3", 9, false},
+		{"10 - 4 - 3", 3, false},
+		{"24 / 4 / 2", 3, false},
+		{"2 ^ 3 ^ 2", 512, false},
+		{"-2 ^ 2", -4, false},
+		{"--3", 3, false},
+		{"1.5e1 + .5", 15.5, false},
+		{"(B08 - B04) / (B08 + B04)", 0.25, false},
+		{"b8 - nir", 0, false},
+		{"min(B02, B03) + max(B04, abs(-B08))", 6, false},
+		{"sqrt(B08 * B08)", 5, false},
+		{"B08 > B04", 1, true},
+		{"B08 <= B04", 0, true},
+		{"B03 == 2 && B04 != 2", 1, true},
+		{"B03 == 2 and B04 == 2", 0, true},
+		{"B03 == 1 or B04 == 3", 1, true},
+		{"not B11 > 1", 1, true},
+		{"!(B11 > 1)", 1, true},
+		{"!B11 > 1 || B02 == 1", 1, true},
+		{"not not B03 == 2", 1, true},
+		{"not B03 == 2 and B04 == 3", 0, true},
+		{"B03 == 2 and not B04 == 2", 1, true},
+		{"1 + 1 > 1 && 0 < 1 || 0", 1, true},
+		{"0 || 1 && 0", 0, true},
+		{"B02 + B03 * 2 > 4", 1, true},
+	}
+
+	for _, test := range tests {
+		expression, err := ParseExpression("test", test.source, testBands)
+		if err != nil {
+			t.Errorf("ParseExpression(%q) failed: %v", test.source, err)
+			continue
+		}
+		got := evalExpression(t, expression, values)
+		if math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("ParseExpression(%q) evaluated to %v, want %v", test.source, got, test.want)
+		}
+		if expression.IsMask() != test.mask {
+			t.Errorf("ParseExpression(%q).IsMask() = %v, want %v", test.source, expression.IsMask(), test.mask)
+		}
+	}
+}
+
+func TestParseExpressionBands(t *testing.T) {
+	expression, err := ParseExpression("test", "(nir - B4) / (B08 + red) + green", testBands)
+	if err != nil {
+		t.Fatalf("ParseExpression failed: %v", err)
+	}
+
+	want := []string{"nir", "B4", "green"}
+	if strings.Join(expression.Bands, ",") != strings.Join(want, ",") {
+		t.Errorf("bands are %v, want %v", expression.Bands, want)
+	}
+}
+
+func TestParseExpressionErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"", "invalid expression at position 0: unexpected 'end of expression'"},
+		{"B04 +", "invalid expression at position 5: unexpected 'end of expression'"},
+		{"(B04 + B08", "invalid expression at position 10: expected ')' but found 'end of expression'"},
+		{"B04 B08", "invalid expression at position 4: unexpected 'B08'"},
+		{"B04 < B08 < 1", "invalid expression at position 10: unexpected '<'"},
+		{"B04 == B08 == 1", "invalid expression at position 11: unexpected '=='"},
+		{"B04 $ B08", "unexpected character '$' at position 4"},
+		{"B04 = B08", "unexpected character '=' at position 4"},
+		{"B99 + 1", "unknown band 'B99' at position 0"},
+		{"B04 + B12", "unknown band 'B12' at position 6, available bands are"},
+		{"min(B04)", "function 'min' at position 0 expects 2 arguments but got 1"},
+		{"sqrt B04", "invalid expression at position 5: expected '(' after function 'sqrt'"},
+		{"max(B04, B08", "invalid expression at position 12: expected ')' but found 'end of expression'"},
+		{"1..2", "invalid number '1..2' at position 0"},
+		{"B04 * !B08", "invalid expression at position 6: unexpected '!'"},
+	}
+
+	for _, test := range tests {
+		_, err := ParseExpression("test", test.source, testBands)
+		if err == nil {
+			t.Errorf("ParseExpression(%q) succeeded, want error %q", test.source, test.want)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.want) {
+			t.Errorf("ParseExpression(%q) error is %q, want %q", test.source, err.Error(), test.want)
+		}
+	}
+}
+
+func TestParseExpressionRegistry(t *testing.T) {
+	_, err := ParseExpression("test", "B12 - VV", nil)
+	if err != nil {
+		t.Errorf("registered bands not accepted without available bands: %v", err)
+	}
+
+	_, err = ParseExpression("test", "B13", nil)
+	if err == nil || !strings.Contains(err.Error(), "unknown band 'B13' at position 0") {
+		t.Errorf("unregistered band error is %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package model
+
+import (
+	"io/ioutil"
+	"math"
+	"path"
+
+	"github.com/pkg/errors"
+	"github.com/uncharted-distil/gdal"
+)
+
+const (
+	// lonLatProj4 is WGS84 with longitude first, which PROJ strings use
+	// regardless of the axis order of EPSG:4326.
+	lonLatProj4 = "+proj=longlat +datum=WGS84 +no_defs"
+
+	// footprintSteps is the number of segments each edge of a footprint is
+	// split into when reprojecting, to follow the curve of the edges.
+	footprintSteps = 4
+)
+
+// Footprint returns the extent of the tile and its projection, from the
+// metadata coordinates when available or else from the geotransform of its
+// first image. It returns a nil extent if the tile has no known location.
+func (t *Tile) Footprint() (*BoundingBox, string, error) {
+	if t.Metadata != nil && t.Metadata.Coordinates != nil && t.Metadata.Projection != "" {
+		return t.Metadata.Coordinates, t.Metadata.Projection, nil
+	}
+
+	image, err := t.firstImage()
+	if err != nil {
+		return nil, "", err
+	}
+	if image == nil || image.Projection == "" {
+		return nil, "", nil
+	}
+
+	return image.Footprint(), image.Projection, nil
+}
+
+// GeographicFootprint returns the extent of the tile in longitude (x) and
+// latitude (y), reprojecting the footprint of the tile to WGS84. It returns a
+// nil extent if the tile has no known location.
+func (t *Tile) GeographicFootprint() (*BoundingBox, error) {
+	box, projection, err := t.Footprint()
+	if err != nil || box == nil {
+		return nil, err
+	}
+
+	geographic, err := ToLonLat(box, projection)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to reproject footprint of '%s'", t.TileName)
+	}
+
+	return geographic, nil
+}
+
+// firstImage returns the first loaded image of the tile, or reads the size
+// and geotransform of the first image file when no images are loaded.
+func (t *Tile) firstImage() (*Image, error) {
+	if len(t.Images) > 0 {
+		return t.Images[0], nil
+	}
+
+	filename := t.GetCompletePath()
+	if !t.MultiBand {
+		imageFiles, err := ioutil.ReadDir(filename)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to read contents of '%s'", filename)
+		}
+
+		filename = ""
+		for _, f := range imageFiles {
+			if !f.IsDir() && path.Ext(f.Name()) != ".json" {
+				filename = path.Join(t.GetCompletePath(), f.Name())
+				break
+			}
+		}
+		if filename == "" {
+			return nil, nil
+		}
+	}
+
+	image := NewImage(filename)
+	err := image.LoadInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	return image, nil
+}
+
+// ToLonLat reprojects an extent from the projection, given as WKT, to an
+// extent in WGS84 longitude (x) and latitude (y). The edges are sampled so
+// the extent covers the curved edges of the reprojected box.
+func ToLonLat(box *BoundingBox, projection string) (*BoundingBox, error) {
+	source := gdal.CreateSpatialReference("")
+	defer source.Destroy()
+	err := source.FromWKT(projection)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse projection")
+	}
+
+	target := gdal.CreateSpatialReference("")
+	defer target.Destroy()
+	err = target.FromProj4(lonLatProj4)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to create geographic projection")
+	}
+
+	transform := gdal.CreateCoordinateTransform(source, target)
+	defer transform.Destroy()
+
+	xs, ys := outline(box)
+	zs := make([]float64, len(xs))
+
+	if !transform.Transform(len(xs), xs, ys, zs) {
+		return nil, errors.Errorf("unable to transform coordinates")
+	}
+
+	return bounds(xs, ys), nil
+}
+
+// outline samples the edges of the box, splitting each edge into
+// footprintSteps segments, going clockwise from the upper left corner.
+func outline(box *BoundingBox) ([]float64, []float64) {
+	xs := make([]float64, 0, footprintSteps*4)
+	ys := make([]float64, 0, footprintSteps*4)
+	for s := 0; s < footprintSteps; s++ {
+		f := float64(s) / footprintSteps
+		dx := f * box.Width()
+		dy := f * box.Height()
+		xs = append(xs, box.ULX+dx, box.LRX, box.LRX-dx, box.ULX)
+		ys = append(ys, box.ULY, box.ULY-dy, box.LRY, box.LRY+dy)
+	}
+
+	return xs, ys
+}
+
+// bounds returns the extent of the points.
+func bounds(xs []float64, ys []float64) *BoundingBox {
+	box := &BoundingBox{
+		ULX: xs[0],
+		ULY: ys[0],
+		LRX: xs[0],
+		LRY: ys[0],
+	}
+	for c := 1; c < len(xs); c++ {
+		box.ULX = math.Min(box.ULX, xs[c])
+		box.LRX = math.Max(box.LRX, xs[c])
+		box.ULY = math.Max(box.ULY, ys[c])
+		box.LRY = math.Min(box.LRY, ys[c])
+	}
+
+	return box
+}
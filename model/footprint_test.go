@@ -0,0 +1,43 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestOutlineCoversEdges(t *testing.T) {
+	box := &BoundingBox{ULX: 399960, ULY: 5000040, LRX: 401160, LRY: 4998840}
+	xs, ys := outline(box)
+	if len(xs) != footprintSteps*4 || len(ys) != len(xs) {
+		t.Fatalf("outline has %d x and %d y values, want %d", len(xs), len(ys), footprintSteps*4)
+	}
+
+	corners := map[[2]float64]bool{}
+	for p := range xs {
+		onEdge := xs[p] == box.ULX || xs[p] == box.LRX || ys[p] == box.ULY || ys[p] == box.LRY
+		inside := xs[p] >= box.ULX && xs[p] <= box.LRX && ys[p] >= box.LRY && ys[p] <= box.ULY
+		if !onEdge || !inside {
+			t.Errorf("outline point (%v, %v) is not on the edge of the box", xs[p], ys[p])
+		}
+		corners[[2]float64{xs[p], ys[p]}] = true
+	}
+	for _, c := range [][2]float64{{box.ULX, box.ULY}, {box.LRX, box.ULY}, {box.LRX, box.LRY}, {box.ULX, box.LRY}} {
+		if !corners[c] {
+			t.Errorf("outline misses corner %v", c)
+		}
+	}
+	if len(corners) != len(xs) {
+		t.Errorf("outline repeats points")
+	}
+
+	if got := bounds(xs, ys); *got != *box {
+		t.Errorf("bounds of the outline are %+v, want %+v", *got, *box)
+	}
+}
+
+func TestBounds(t *testing.T) {
+	got := bounds([]float64{10, -5, 3}, []float64{45, 50, 40})
+	want := BoundingBox{ULX: -5, ULY: 50, LRX: 10, LRY: 40}
+	if *got != want {
+		t.Errorf("bounds are %+v, want %+v", *got, want)
+	}
+}
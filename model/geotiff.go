@@ -0,0 +1,104 @@
+package model
+
+import (
+	"github.com/pkg/errors"
+	"github.com/uncharted-distil/gdal"
+)
+
+// WriteGeoTIFF writes the images as the bands of one GeoTIFF. The images must
+// all be the same size and are georeferenced using the first image. Bands
+// are stored using the common data type of the images, falling back to
+// float32 when they differ. The metadata is written to the default domain.
+func WriteGeoTIFF(filename string, images []*Image, metadata map[string]string) error {
+	if len(images) == 0 {
+		return errors.Errorf("no bands to write to '%s'", filename)
+	}
+	reference := images[0]
+
+	dataType := reference.DataType
+	for _, img := range images {
+		if img.SizeX != reference.SizeX || img.SizeY != reference.SizeY {
+			return errors.Errorf("band '%s' size %dx%d does not match band '%s' size %dx%d",
+				img.Band, img.SizeX, img.SizeY, reference.Band, reference.SizeX, reference.SizeY)
+		}
+		if img.DataType != dataType {
+			dataType = DataTypeFloat32
+		}
+	}
+
+	driver, err := gdal.GetDriverByName("GTiff")
+	if err != nil {
+		return errors.Wrap(err, "unable to load geotiff driver")
+	}
+	dataset := driver.Create(filename, reference.SizeX, reference.SizeY, len(images), dataType.gdalDataType(), []string{"COMPRESS=DEFLATE"})
+	defer dataset.Close()
+
+	err = dataset.SetGeoTransform(reference.GeoTransform)
+	if err != nil {
+		return errors.Wrapf(err, "unable to set geotransform of '%s'", filename)
+	}
+	if reference.Projection != "" {
+		err = dataset.SetProjection(reference.Projection)
+		if err != nil {
+			return errors.Wrapf(err, "unable to set projection of '%s'", filename)
+		}
+	}
+
+	for name, value := range metadata {
+		err = dataset.SetMetadataItem(name, value, "")
+		if err != nil {
+			return errors.Wrapf(err, "unable to set metadata '%s' of '%s'", name, filename)
+		}
+	}
+
+	for b, img := range images {
+		rasterBand := dataset.RasterBand(b + 1)
+		err = (&rasterBand).SetMetadataItem("band", img.Band, "")
+		if err != nil {
+			return errors.Wrapf(err, "unable to set the name of band '%s'", img.Band)
+		}
+		if img.HasNoData {
+			err = rasterBand.SetNoDataValue(img.NoData)
+			if err != nil {
+				return errors.Wrapf(err, "unable to set nodata value of band '%s'", img.Band)
+			}
+		}
+
+		err = rasterBand.IO(gdal.Write, 0, 0, img.SizeX, img.SizeY, img.pixelBuffer(), img.SizeX, img.SizeY, 0, 0)
+		if err != nil {
+			return errors.Wrapf(err, "unable to write band '%s' to '%s'", img.Band, filename)
+		}
+	}
+
+	return nil
+}
+
+func (dt DataType) gdalDataType() gdal.DataType {
+	switch dt {
+	case DataTypeUint8:
+		return gdal.Byte
+	case DataTypeUint16:
+		return gdal.UInt16
+	case DataTypeInt16:
+		return gdal.Int16
+	case DataTypeFloat32:
+		return gdal.Float32
+	}
+
+	return gdal.Unknown
+}
+
+func (i *Image) pixelBuffer() interface{} {
+	switch i.DataType {
+	case DataTypeUint8:
+		return i.PixelsUint8
+	case DataTypeUint16:
+		return i.PixelsUint16
+	case DataTypeInt16:
+		return i.PixelsInt16
+	case DataTypeFloat32:
+		return i.PixelsFloat32
+	}
+
+	return nil
+}
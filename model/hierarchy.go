@@ -0,0 +1,156 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// NomenclatureCorineLevelPrefix prefixes the names of the nomenclatures
+	// rolling labels up the CORINE hierarchy.
+	NomenclatureCorineLevelPrefix = "corine-level-"
+)
+
+var (
+	// corineCodes are the level 3 CORINE codes of the original labels.
+	corineCodes = []string{
+		"111", "112", "121", "122", "123", "124", "131", "132", "133", "141", "142",
+		"211", "212", "213", "221", "222", "223", "231", "241", "242", "243", "244",
+		"311", "312", "313", "321", "322", "323", "324", "331", "332", "333", "334",
+		"411", "412", "421", "422", "423",
+		"511", "512", "521", "522", "523",
+	}
+
+	corineGroups = map[string]string{
+		"1":  "Artificial surfaces",
+		"11": "Urban fabric",
+		"12": "Industrial, commercial and transport units",
+		"13": "Mine, dump and construction sites",
+		"14": "Artificial, non-agricultural vegetated areas",
+		"2":  "Agricultural areas",
+		"21": "Arable land",
+		"22": "Permanent crops",
+		"23": "Pastures",
+		"24": "Heterogeneous agricultural areas",
+		"3":  "Forest and semi natural areas",
+		"31": "Forests",
+		"32": "Scrub and/or herbaceous vegetation associations",
+		"33": "Open spaces with little or no vegetation",
+		"4":  "Wetlands",
+		"41": "Inland wetlands",
+		"42": "Maritime wetlands",
+		"5":  "Water bodies",
+		"51": "Inland waters",
+		"52": "Marine waters",
+	}
+
+	corineClasses = createCorineClasses()
+)
+
+// CorineClass is a class of the three level CORINE Land Cover hierarchy.
+type CorineClass struct {
+	Code  string
+	Name  string
+	Level int
+}
+
+func createCorineClasses() map[string]*CorineClass {
+	classes := make(map[string]*CorineClass)
+	for i, label := range OriginalLabels {
+		classes[label] = &CorineClass{
+			Code:  corineCodes[i],
+			Name:  label,
+			Level: 3,
+		}
+	}
+
+	return classes
+}
+
+// LookupCorineClass returns the level 3 class of an original label.
+func LookupCorineClass(label string) (*CorineClass, bool) {
+	class, ok := corineClasses[label]
+	return class, ok
+}
+
+// Ancestor returns the class containing this class at the specified level.
+func (c *CorineClass) Ancestor(level int) *CorineClass {
+	if level >= c.Level || level < 1 {
+		return c
+	}
+
+	code := c.Code[:level]
+	return &CorineClass{
+		Code:  code,
+		Name:  corineGroups[code],
+		Level: level,
+	}
+}
+
+// CorineClassesAtLevel returns the names of the classes of a level of the
+// hierarchy ordered by code.
+func CorineClassesAtLevel(level int) []string {
+	codes := make([]string, 0)
+	names := make(map[string]string)
+	if level >= 3 {
+		for label, class := range corineClasses {
+			codes = append(codes, class.Code)
+			names[class.Code] = label
+		}
+	} else {
+		for code, name := range corineGroups {
+			if len(code) == level {
+				codes = append(codes, code)
+				names[code] = name
+			}
+		}
+	}
+	sort.Strings(codes)
+
+	classes := make([]string, len(codes))
+	for i, code := range codes {
+		classes[i] = names[code]
+	}
+
+	return classes
+}
+
+// RollUpLabels returns the classes of the labels at the specified level in
+// the order they first appear. Labels outside the hierarchy are dropped.
+func RollUpLabels(labels []string, level int) []string {
+	rolled := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, label := range labels {
+		class, ok := LookupCorineClass(label)
+		if !ok {
+			continue
+		}
+		name := class.Ancestor(level).Name
+		if !seen[name] {
+			seen[name] = true
+			rolled = append(rolled, name)
+		}
+	}
+
+	return rolled
+}
+
+// NewCorineNomenclature creates the nomenclature of a level of the CORINE
+// hierarchy, converting the original labels to their class at that level.
+func NewCorineNomenclature(level int) (*Nomenclature, error) {
+	if level < 1 || level > 3 {
+		return nil, errors.Errorf("CORINE level %d must be 1, 2 or 3", level)
+	}
+
+	n := newNomenclature(fmt.Sprintf("%s%d", NomenclatureCorineLevelPrefix, level))
+	for _, class := range CorineClassesAtLevel(level) {
+		n.addClass(class, []string{})
+	}
+	for _, label := range OriginalLabels {
+		n.conversion[label] = corineClasses[label].Ancestor(level).Name
+	}
+
+	return n, nil
+}
@@ -0,0 +1,100 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCorineClassAncestor(t *testing.T) {
+	tests := []struct {
+		label string
+		level int
+		code  string
+		name  string
+	}{
+		{"Vineyards", 3, "221", "Vineyards"},
+		{"Vineyards", 2, "22", "Permanent crops"},
+		{"Vineyards", 1, "2", "Agricultural areas"},
+		{"Peatbogs", 2, "41", "Inland wetlands"},
+		{"Salines", 2, "42", "Maritime wetlands"},
+		{"Sea and ocean", 1, "5", "Water bodies"},
+		{"Airports", 2, "12", "Industrial, commercial and transport units"},
+		{"Airports", 0, "124", "Airports"},
+	}
+
+	for _, test := range tests {
+		class, ok := LookupCorineClass(test.label)
+		if !ok {
+			t.Fatalf("label %s not in the hierarchy", test.label)
+		}
+		ancestor := class.Ancestor(test.level)
+		if ancestor.Code != test.code || ancestor.Name != test.name {
+			t.Errorf("level %d ancestor of %s is %s %s, want %s %s", test.level, test.label, ancestor.Code, ancestor.Name, test.code, test.name)
+		}
+	}
+
+	if _, ok := LookupCorineClass("Urban fabric"); ok {
+		t.Errorf("level 2 class found as a level 3 class")
+	}
+}
+
+func TestCorineClassesAtLevel(t *testing.T) {
+	tests := []struct {
+		level int
+		count int
+		first string
+		last  string
+	}{
+		{1, 5, "Artificial surfaces", "Water bodies"},
+		{2, 15, "Urban fabric", "Marine waters"},
+		{3, 43, "Continuous urban fabric", "Sea and ocean"},
+	}
+
+	for _, test := range tests {
+		classes := CorineClassesAtLevel(test.level)
+		if len(classes) != test.count || classes[0] != test.first || classes[len(classes)-1] != test.last {
+			t.Errorf("level %d classes are %v", test.level, classes)
+		}
+	}
+}
+
+func TestRollUpLabels(t *testing.T) {
+	labels := []string{"Vineyards", "Sea and ocean", "Olive groves", "Unknown label", "Coniferous forest"}
+	tests := []struct {
+		level int
+		want  []string
+	}{
+		{1, []string{"Agricultural areas", "Water bodies", "Forest and semi natural areas"}},
+		{2, []string{"Permanent crops", "Marine waters", "Forests"}},
+		{3, []string{"Vineyards", "Sea and ocean", "Olive groves", "Coniferous forest"}},
+	}
+
+	for _, test := range tests {
+		if got := RollUpLabels(labels, test.level); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("level %d roll up is %v, want %v", test.level, got, test.want)
+		}
+	}
+}
+
+func TestCorineNomenclature(t *testing.T) {
+	n, err := NewCorineNomenclature(2)
+	if err != nil {
+		t.Fatalf("NewCorineNomenclature failed: %v", err)
+	}
+	if len(n.Classes) != 15 {
+		t.Errorf("level 2 nomenclature has %d classes", len(n.Classes))
+	}
+
+	labels := []string{"Continuous urban fabric", "Discontinuous urban fabric", "Peatbogs", "Forests", "Unknown label"}
+	want := []string{"Urban fabric", "Inland wetlands", "Forests"}
+	if got := n.Convert(labels); !reflect.DeepEqual(got, want) {
+		t.Errorf("Convert(%v) is %v, want %v", labels, got, want)
+	}
+
+	for _, level := range []int{0, 4} {
+		_, err := NewCorineNomenclature(level)
+		if err == nil {
+			t.Errorf("NewCorineNomenclature(%d) did not fail", level)
+		}
+	}
+}
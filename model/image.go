@@ -0,0 +1,291 @@
+package model
+
+import (
+	"math"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/uncharted-distil/gdal"
+)
+
+// DataType is the type of the pixels stored in an image.
+type DataType int
+
+const (
+	// DataTypeUnknown is used for images that have not been loaded.
+	DataTypeUnknown DataType = iota
+	// DataTypeUint8 images store pixels in PixelsUint8.
+	DataTypeUint8
+	// DataTypeUint16 images store pixels in PixelsUint16.
+	DataTypeUint16
+	// DataTypeInt16 images store pixels in PixelsInt16.
+	DataTypeInt16
+	// DataTypeFloat32 images store pixels in PixelsFloat32.
+	DataTypeFloat32
+)
+
+// Image is a single band of a tile. Only the pixel buffer matching the
+// data type of the image is populated. The geotransform follows the GDAL
+// convention, mapping pixel coordinates to coordinates in the projection.
+type Image struct {
+	Band          string
+	Filename      string
+	BandIndex     int
+	SizeX         int
+	SizeY         int
+	GeoTransform  [6]float64
+	Projection    string
+	NoData        float64
+	HasNoData     bool
+	DataType      DataType
+	PixelsUint8   []uint8
+	PixelsUint16  []uint16
+	PixelsInt16   []int16
+	PixelsFloat32 []float32
+}
+
+func NewImage(filename string) *Image {
+	band := extractBand(filename)
+
+	return &Image{
+		Band:      band,
+		Filename:  filename,
+		BandIndex: 1,
+	}
+}
+
+// String returns the name of the data type.
+func (dt DataType) String() string {
+	switch dt {
+	case DataTypeUint8:
+		return "uint8"
+	case DataTypeUint16:
+		return "uint16"
+	case DataTypeInt16:
+		return "int16"
+	case DataTypeFloat32:
+		return "float32"
+	}
+
+	return "unknown"
+}
+
+// Load reads the pixels of the image band from disk. Byte, UInt16, Int16 and
+// Float32 rasters are stored as is while other real valued rasters are
+// converted to float32.
+func (i *Image) Load() error {
+	dataset, err := gdal.Open(i.Filename, gdal.ReadOnly)
+	if err != nil {
+		return errors.Wrapf(err, "unable to open raster '%s'", i.Filename)
+	}
+	defer dataset.Close()
+
+	return i.loadBand(dataset)
+}
+
+// LoadInfo reads the size, geotransform and projection of the image without
+// reading its pixels.
+func (i *Image) LoadInfo() error {
+	dataset, err := gdal.Open(i.Filename, gdal.ReadOnly)
+	if err != nil {
+		return errors.Wrapf(err, "unable to open raster '%s'", i.Filename)
+	}
+	defer dataset.Close()
+
+	i.loadInfo(dataset)
+
+	return nil
+}
+
+func (i *Image) loadInfo(dataset gdal.Dataset) {
+	i.SizeX = dataset.RasterXSize()
+	i.SizeY = dataset.RasterYSize()
+	i.GeoTransform = dataset.GeoTransform()
+	i.Projection = dataset.Projection()
+}
+
+func (i *Image) loadBand(dataset gdal.Dataset) error {
+	if i.BandIndex < 1 || i.BandIndex > dataset.RasterCount() {
+		return errors.Errorf("band %d not found in '%s' (%d bands)", i.BandIndex, i.Filename, dataset.RasterCount())
+	}
+	rasterBand := dataset.RasterBand(i.BandIndex)
+
+	i.loadInfo(dataset)
+	i.NoData, i.HasNoData = rasterBand.NoDataValue()
+	pixelCount := i.SizeX * i.SizeY
+
+	var buffer interface{}
+	switch rasterBand.RasterDataType() {
+	case gdal.Byte:
+		i.DataType = DataTypeUint8
+		i.PixelsUint8 = make([]uint8, pixelCount)
+		buffer = i.PixelsUint8
+	case gdal.UInt16:
+		i.DataType = DataTypeUint16
+		i.PixelsUint16 = make([]uint16, pixelCount)
+		buffer = i.PixelsUint16
+	case gdal.Int16:
+		i.DataType = DataTypeInt16
+		i.PixelsInt16 = make([]int16, pixelCount)
+		buffer = i.PixelsInt16
+	case gdal.Float32, gdal.Float64, gdal.Int32, gdal.UInt32:
+		i.DataType = DataTypeFloat32
+		i.PixelsFloat32 = make([]float32, pixelCount)
+		buffer = i.PixelsFloat32
+	default:
+		return errors.Errorf("unsupported pixel data type %s in '%s'", rasterBand.RasterDataType().Name(), i.Filename)
+	}
+
+	err := rasterBand.IO(gdal.Read, 0, 0, i.SizeX, i.SizeY, buffer, i.SizeX, i.SizeY, 0, 0)
+	if err != nil {
+		return errors.Wrapf(err, "unable to read band %d from '%s'", i.BandIndex, i.Filename)
+	}
+
+	return nil
+}
+
+// PixelCount returns the number of pixels in the image.
+func (i *Image) PixelCount() int {
+	return i.SizeX * i.SizeY
+}
+
+// Value returns the pixel at the specified index as a float64.
+func (i *Image) Value(index int) float64 {
+	switch i.DataType {
+	case DataTypeUint8:
+		return float64(i.PixelsUint8[index])
+	case DataTypeUint16:
+		return float64(i.PixelsUint16[index])
+	case DataTypeInt16:
+		return float64(i.PixelsInt16[index])
+	case DataTypeFloat32:
+		return float64(i.PixelsFloat32[index])
+	}
+
+	return 0
+}
+
+// setValue sets the pixel at the specified index, converting the value to the
+// data type of the image.
+func (i *Image) setValue(index int, value float64) {
+	switch i.DataType {
+	case DataTypeUint8:
+		i.PixelsUint8[index] = uint8(value)
+	case DataTypeUint16:
+		i.PixelsUint16[index] = uint16(value)
+	case DataTypeInt16:
+		i.PixelsInt16[index] = int16(value)
+	case DataTypeFloat32:
+		i.PixelsFloat32[index] = float32(value)
+	}
+}
+
+// allocatePixels creates the pixel buffer matching the data type of the image.
+func (i *Image) allocatePixels() {
+	switch i.DataType {
+	case DataTypeUint8:
+		i.PixelsUint8 = make([]uint8, i.PixelCount())
+	case DataTypeUint16:
+		i.PixelsUint16 = make([]uint16, i.PixelCount())
+	case DataTypeInt16:
+		i.PixelsInt16 = make([]int16, i.PixelCount())
+	case DataTypeFloat32:
+		i.PixelsFloat32 = make([]float32, i.PixelCount())
+	}
+}
+
+// Float64Pixels returns a copy of the pixels converted to float64.
+func (i *Image) Float64Pixels() []float64 {
+	pixels := make([]float64, i.PixelCount())
+	for p := range pixels {
+		pixels[p] = i.Value(p)
+	}
+
+	return pixels
+}
+
+// IsNoData returns true if the pixel at the specified index is the nodata value.
+func (i *Image) IsNoData(index int) bool {
+	if !i.HasNoData {
+		return false
+	}
+	if math.IsNaN(i.NoData) {
+		return math.IsNaN(i.Value(index))
+	}
+
+	return i.Value(index) == i.NoData
+}
+
+// PixelToWorld converts pixel coordinates to coordinates in the projection of
+// the image. Pixel (0, 0) is the upper left corner of the upper left pixel.
+func (i *Image) PixelToWorld(px float64, py float64) (float64, float64) {
+	gt := i.GeoTransform
+	x := gt[0] + px*gt[1] + py*gt[2]
+	y := gt[3] + px*gt[4] + py*gt[5]
+
+	return x, y
+}
+
+// WorldToPixel converts coordinates in the projection of the image to pixel
+// coordinates. It fails if the geotransform cannot be inverted.
+func (i *Image) WorldToPixel(x float64, y float64) (float64, float64, error) {
+	gt := i.GeoTransform
+	det := gt[1]*gt[5] - gt[2]*gt[4]
+	if math.Abs(det) < 1e-15 {
+		return 0, 0, errors.Errorf("geotransform of '%s' is not invertible", i.Filename)
+	}
+
+	dx := x - gt[0]
+	dy := y - gt[3]
+	px := (dx*gt[5] - dy*gt[2]) / det
+	py := (dy*gt[1] - dx*gt[4]) / det
+
+	return px, py, nil
+}
+
+// Footprint returns the extent of the image in the coordinates of its projection.
+func (i *Image) Footprint() *BoundingBox {
+	xs := make([]float64, 4)
+	ys := make([]float64, 4)
+	xs[0], ys[0] = i.PixelToWorld(0, 0)
+	xs[1], ys[1] = i.PixelToWorld(float64(i.SizeX), 0)
+	xs[2], ys[2] = i.PixelToWorld(0, float64(i.SizeY))
+	xs[3], ys[3] = i.PixelToWorld(float64(i.SizeX), float64(i.SizeY))
+
+	box := &BoundingBox{
+		ULX: xs[0],
+		ULY: ys[0],
+		LRX: xs[0],
+		LRY: ys[0],
+	}
+	for c := 1; c < 4; c++ {
+		box.ULX = math.Min(box.ULX, xs[c])
+		box.LRX = math.Max(box.LRX, xs[c])
+		box.ULY = math.Max(box.ULY, ys[c])
+		box.LRY = math.Min(box.LRY, ys[c])
+	}
+
+	return box
+}
+
+func extractBand(filename string) string {
+	bandRaw := bandRegex.Find([]byte(filename))
+	if len(bandRaw) > 0 {
+		band := string(bandRaw)
+		return strings.ToLower(band[2 : len(band)-1])
+	}
+
+	s1BandRaw := s1BandRegex.FindStringSubmatch(filename)
+	if len(s1BandRaw) > 1 {
+		return strings.ToLower(s1BandRaw[1])
+	}
+
+	// derived bands are named after the band without the B prefix
+	derivedRaw := derivedBandRegex.FindStringSubmatch(path.Base(filename))
+	if len(derivedRaw) > 1 {
+		return strings.ToLower(derivedRaw[1])
+	}
+
+	return ""
+}
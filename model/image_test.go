@@ -0,0 +1,49 @@
+package model
+
+import (
+	"math"
+	"testing"
+)
+
+func TestImageIsNoData(t *testing.T) {
+	nan := float32(math.NaN())
+	tests := []struct {
+		name string
+		img  *Image
+		want []bool
+	}{
+		{
+			name: "uint16 zero nodata",
+			img:  &Image{DataType: DataTypeUint16, NoData: 0, HasNoData: true, PixelsUint16: []uint16{0, 1, 65535}},
+			want: []bool{true, false, false},
+		},
+		{
+			name: "int16 negative nodata",
+			img:  &Image{DataType: DataTypeInt16, NoData: -9999, HasNoData: true, PixelsInt16: []int16{-9999, 0, 9999}},
+			want: []bool{true, false, false},
+		},
+		{
+			name: "float32 NaN nodata",
+			img:  &Image{DataType: DataTypeFloat32, NoData: math.NaN(), HasNoData: true, PixelsFloat32: []float32{nan, 0, 0.5}},
+			want: []bool{true, false, false},
+		},
+		{
+			name: "float32 value nodata",
+			img:  &Image{DataType: DataTypeFloat32, NoData: -1, HasNoData: true, PixelsFloat32: []float32{-1, nan, 0}},
+			want: []bool{true, false, false},
+		},
+		{
+			name: "without nodata",
+			img:  &Image{DataType: DataTypeUint8, NoData: 0, PixelsUint8: []uint8{0, 1, 255}},
+			want: []bool{false, false, false},
+		},
+	}
+
+	for _, test := range tests {
+		for p, want := range test.want {
+			if got := test.img.IsNoData(p); got != want {
+				t.Errorf("%s pixel %d nodata is %v, want %v", test.name, p, got, want)
+			}
+		}
+	}
+}
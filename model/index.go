@@ -0,0 +1,170 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// s2ReflectanceScale is the quantification value of the Sentinel-2 products.
+	s2ReflectanceScale = 10000.0
+)
+
+var (
+	spectralIndices = []*SpectralIndex{
+		normalizedDifference("NDVI", "Normalized difference vegetation index", "B08", "B04"),
+		normalizedDifference("NDWI", "Normalized difference water index", "B03", "B08"),
+		normalizedDifference("MNDWI", "Modified normalized difference water index", "B03", "B11"),
+		normalizedDifference("NDBI", "Normalized difference built-up index", "B11", "B08"),
+		normalizedDifference("NBR", "Normalized burn ratio", "B08", "B12"),
+		normalizedDifference("NDMI", "Normalized difference moisture index", "B08", "B11"),
+		normalizedDifference("NDRE", "Normalized difference red edge index", "B08", "B05"),
+		normalizedDifference("NDSI", "Normalized difference snow index", "B03", "B11"),
+		{
+			Name:        "SAVI",
+			Description: "Soil adjusted vegetation index",
+			Bands:       []string{"B08", "B04"},
+			Formula: func(v []float64) float64 {
+				return 1.5 * (v[0] - v[1]) / (v[0] + v[1] + 0.5)
+			},
+		},
+		{
+			Name:        "EVI",
+			Description: "Enhanced vegetation index",
+			Bands:       []string{"B08", "B04", "B02"},
+			Formula: func(v []float64) float64 {
+				return 2.5 * (v[0] - v[1]) / (v[0] + 6*v[1] - 7.5*v[2] + 1)
+			},
+		},
+	}
+)
+
+// SpectralIndex is an index computed per pixel from the reflectance of
+// registered bands. The formula receives the values of the bands in order.
+type SpectralIndex struct {
+	Name        string
+	Description string
+	Bands       []string
+	Formula     func(values []float64) float64
+}
+
+func normalizedDifference(name string, description string, positive string, negative string) *SpectralIndex {
+	return &SpectralIndex{
+		Name:        name,
+		Description: description,
+		Bands:       []string{positive, negative},
+		Formula: func(v []float64) float64 {
+			return (v[0] - v[1]) / (v[0] + v[1])
+		},
+	}
+}
+
+// ListIndices returns all the supported spectral indices.
+func ListIndices() []*SpectralIndex {
+	return spectralIndices
+}
+
+// LookupIndex returns the spectral index matching the name.
+func LookupIndex(name string) (*SpectralIndex, bool) {
+	for _, index := range spectralIndices {
+		if strings.EqualFold(index.Name, strings.TrimSpace(name)) {
+			return index, true
+		}
+	}
+
+	return nil, false
+}
+
+// ParseIndices parses a comma separated list of spectral index names.
+func ParseIndices(names string) ([]*SpectralIndex, error) {
+	indices := make([]*SpectralIndex, 0)
+	if names == "" {
+		return indices, nil
+	}
+
+	for _, name := range strings.Split(names, ",") {
+		index, ok := LookupIndex(name)
+		if !ok {
+			return nil, errors.Errorf("unknown spectral index '%s'", name)
+		}
+		indices = append(indices, index)
+	}
+
+	return indices, nil
+}
+
+// ComputeIndex computes the spectral index for every pixel of the tile on the
+// grid of its finest input band. Pixels where the index is undefined are set
+// to NaN, which is also the nodata value of the resulting image.
+func (t *Tile) ComputeIndex(index *SpectralIndex) (*Image, error) {
+	if len(t.Images) == 0 {
+		err := t.LoadFiles()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	missing := t.MissingBands(index.Bands)
+	if len(missing) > 0 {
+		return nil, errors.Errorf("tile '%s' is missing bands %v required by %s", t.TileName, missing, index.Name)
+	}
+
+	cube, err := t.DataCube(0, ResampleBilinear, index.Bands)
+	if err != nil {
+		return nil, err
+	}
+
+	// scale digital numbers to reflectance
+	scales := make([]float64, len(index.Bands))
+	for b, band := range index.Bands {
+		scales[b] = 1
+		info, ok := LookupBand(band)
+		if ok && info.Sensor == SensorSentinel2 {
+			scales[b] = s2ReflectanceScale
+		}
+	}
+
+	img := newDerivedImage(strings.ToLower(index.Name), cube)
+	values := make([]float64, len(index.Bands))
+	for y := 0; y < cube.SizeY; y++ {
+		for x := 0; x < cube.SizeX; x++ {
+			for b := range values {
+				values[b] = float64(cube.Data[b][y][x]) / scales[b]
+			}
+			value := index.Formula(values)
+			if math.IsInf(value, 0) {
+				value = math.NaN()
+			}
+			img.PixelsFloat32[y*cube.SizeX+x] = float32(value)
+		}
+	}
+
+	return img, nil
+}
+
+// WriteDerivedBand writes a band computed from the tile as a single band
+// GeoTIFF named after the tile and the band in the specified folder.
+func (t *Tile) WriteDerivedBand(folder string, img *Image) error {
+	tileName := strings.TrimSuffix(t.TileName, path.Ext(t.TileName))
+	filename := path.Join(folder, fmt.Sprintf("%s_%s.tif", tileName, strings.ToUpper(img.Band)))
+
+	return WriteGeoTIFF(filename, []*Image{img}, nil)
+}
+
+func newDerivedImage(band string, cube *DataCube) *Image {
+	return &Image{
+		Band:          band,
+		SizeX:         cube.SizeX,
+		SizeY:         cube.SizeY,
+		GeoTransform:  cube.GeoTransform,
+		Projection:    cube.Projection,
+		NoData:        math.NaN(),
+		HasNoData:     true,
+		DataType:      DataTypeFloat32,
+		PixelsFloat32: make([]float32, cube.SizeX*cube.SizeY),
+	}
+}
@@ -0,0 +1,131 @@
+package model
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSpectralIndexFormulas(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   float64
+	}{
+		{"NDVI", []float64{0.5, 0.1}, 0.4 / 0.6},
+		{"NDVI", []float64{0.1, 0.1}, 0},
+		{"NDWI", []float64{0.1, 0.3}, -0.5},
+		{"MNDWI", []float64{0.3, 0.1}, 0.5},
+		{"NDBI", []float64{0.3, 0.2}, 0.2},
+		{"NBR", []float64{0.4, 0.1}, 0.6},
+		{"NDMI", []float64{0.4, 0.4}, 0},
+		{"NDRE", []float64{0.6, 0.2}, 0.5},
+		{"NDSI", []float64{0.8, 0.2}, 0.6},
+		{"SAVI", []float64{0.5, 0.1}, 1.5 * 0.4 / 1.1},
+		{"EVI", []float64{0.5, 0.1, 0.05}, 2.5 * 0.4 / 1.725},
+	}
+
+	for _, test := range tests {
+		index, ok := LookupIndex(test.name)
+		if !ok {
+			t.Fatalf("index %s not found", test.name)
+		}
+		if len(index.Bands) != len(test.values) {
+			t.Fatalf("index %s uses %d bands, test has %d values", test.name, len(index.Bands), len(test.values))
+		}
+		if got := index.Formula(test.values); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%s of %v is %v, want %v", test.name, test.values, got, test.want)
+		}
+	}
+}
+
+func TestLookupIndex(t *testing.T) {
+	tests := []struct {
+		name  string
+		found string
+	}{
+		{"NDVI", "NDVI"},
+		{"ndvi", "NDVI"},
+		{" Evi ", "EVI"},
+		{"ndwi2", ""},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		index, ok := LookupIndex(test.name)
+		if ok != (test.found != "") {
+			t.Errorf("index %q found is %v", test.name, ok)
+		} else if ok && index.Name != test.found {
+			t.Errorf("index %q is %s, want %s", test.name, index.Name, test.found)
+		}
+	}
+}
+
+func TestParseIndices(t *testing.T) {
+	indices, err := ParseIndices("ndvi, NBR,savi")
+	if err != nil {
+		t.Fatalf("ParseIndices failed: %v", err)
+	}
+	want := []string{"NDVI", "NBR", "SAVI"}
+	if len(indices) != len(want) {
+		t.Fatalf("parsed %d indices, want %d", len(indices), len(want))
+	}
+	for i, index := range indices {
+		if index.Name != want[i] {
+			t.Errorf("index %d is %s, want %s", i, index.Name, want[i])
+		}
+	}
+
+	indices, err = ParseIndices("")
+	if err != nil || len(indices) != 0 {
+		t.Errorf("ParseIndices of no names is %v, %v", indices, err)
+	}
+
+	for _, names := range []string{"ndvi,unknown", "ndvi,", "ndvi;nbr"} {
+		_, err := ParseIndices(names)
+		if err == nil {
+			t.Errorf("ParseIndices(%s) did not fail", names)
+		}
+	}
+}
+
+func TestTileComputeIndex(t *testing.T) {
+	nir := &Image{
+		Band:         "08",
+		SizeX:        2,
+		SizeY:        1,
+		GeoTransform: [6]float64{0, 10, 0, 0, 0, -10},
+		DataType:     DataTypeUint16,
+		PixelsUint16: []uint16{5000, 0},
+	}
+	red := &Image{
+		Band:         "04",
+		SizeX:        2,
+		SizeY:        1,
+		GeoTransform: [6]float64{0, 10, 0, 0, 0, -10},
+		DataType:     DataTypeUint16,
+		PixelsUint16: []uint16{1000, 0},
+	}
+	tile := &Tile{TileName: "S2A_MSIL2A_20170613T101031_0_45", Images: []*Image{nir, red}}
+
+	ndvi, _ := LookupIndex("NDVI")
+	img, err := tile.ComputeIndex(ndvi)
+	if err != nil {
+		t.Fatalf("ComputeIndex failed: %v", err)
+	}
+	if img.Band != "ndvi" || img.SizeX != 2 || img.SizeY != 1 || !img.HasNoData {
+		t.Errorf("index image is band %s of %dx%d with nodata %v", img.Band, img.SizeX, img.SizeY, img.HasNoData)
+	}
+	if math.Abs(float64(img.PixelsFloat32[0])-0.4/0.6) > 1e-6 {
+		t.Errorf("NDVI pixel is %v, want %v", img.PixelsFloat32[0], 0.4/0.6)
+	}
+	// an undefined index is nodata
+	if !math.IsNaN(float64(img.PixelsFloat32[1])) {
+		t.Errorf("undefined NDVI pixel is %v, want NaN", img.PixelsFloat32[1])
+	}
+
+	nbr, _ := LookupIndex("NBR")
+	_, err = tile.ComputeIndex(nbr)
+	if err == nil {
+		t.Errorf("ComputeIndex without band B12 did not fail")
+	}
+}
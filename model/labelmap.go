@@ -0,0 +1,136 @@
+package model
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// LabelMap renames labels, dropping the labels mapped to "". Labels missing
+// from the map are kept as is.
+type LabelMap struct {
+	Filename string
+	mapping  map[string]string
+}
+
+// LoadLabelMap reads a label map from a JSON object of old to new labels or
+// from a CSV file with old and new label columns. No filename results in an
+// empty map.
+func LoadLabelMap(filename string) (*LabelMap, error) {
+	labelMap := &LabelMap{
+		Filename: filename,
+		mapping:  make(map[string]string),
+	}
+	if filename == "" {
+		return labelMap, nil
+	}
+
+	if strings.ToLower(path.Ext(filename)) == ".json" {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to read label map '%s'", filename)
+		}
+		err = json.Unmarshal(data, &labelMap.mapping)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to unmarshal label map '%s'", filename)
+		}
+
+		return labelMap, nil
+	}
+
+	csvFile, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to open label map '%s'", filename)
+	}
+	defer csvFile.Close()
+	reader := csv.NewReader(csvFile)
+	reader.FieldsPerRecord = -1
+
+	first := true
+	for {
+		line, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "unable to read label map '%s'", filename)
+		}
+
+		// skip the optional header
+		if first && len(line) == 2 && strings.EqualFold(line[0], "old") && strings.EqualFold(line[1], "new") {
+			first = false
+			continue
+		}
+		first = false
+
+		if len(line) == 1 {
+			line = append(line, "")
+		}
+		if len(line) != 2 {
+			return nil, errors.Errorf("label map '%s' line %v not in the format old,new", filename, line)
+		}
+		labelMap.mapping[line[0]] = line[1]
+	}
+
+	return labelMap, nil
+}
+
+// Apply maps the labels in order, dropping duplicates and dropped labels.
+func (lm *LabelMap) Apply(labels []string) []string {
+	if len(lm.mapping) == 0 {
+		return labels
+	}
+
+	mapped := make([]string, 0, len(labels))
+	seen := make(map[string]bool)
+	for _, label := range labels {
+		if newLabel, ok := lm.mapping[label]; ok {
+			label = newLabel
+		}
+		if label != "" && !seen[label] {
+			seen[label] = true
+			mapped = append(mapped, label)
+		}
+	}
+
+	return mapped
+}
+
+// Empty returns true if the map does not change any label.
+func (lm *LabelMap) Empty() bool {
+	return len(lm.mapping) == 0
+}
+
+// Entries returns the old and new label pairs sorted by old label.
+func (lm *LabelMap) Entries() [][2]string {
+	entries := make([][2]string, 0, len(lm.mapping))
+	for o, n := range lm.mapping {
+		entries = append(entries, [2]string{o, n})
+	}
+	sort.Slice(entries, func(i int, j int) bool {
+		return entries[i][0] < entries[j][0]
+	})
+
+	return entries
+}
+
+// Write stores the label map as JSON so it can be reused to reproduce results.
+func (lm *LabelMap) Write(filename string) error {
+	data, err := json.MarshalIndent(lm.mapping, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal label map")
+	}
+
+	err = ioutil.WriteFile(filename, data, os.ModePerm)
+	if err != nil {
+		return errors.Wrapf(err, "unable to write label map to '%s'", filename)
+	}
+
+	return nil
+}
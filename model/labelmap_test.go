@@ -0,0 +1,144 @@
+package model
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+)
+
+// loadTestLabelMap writes the label map contents to a file with the given
+// name in a temporary folder and loads it.
+func loadTestLabelMap(t *testing.T, name string, contents string) (*LabelMap, error) {
+	t.Helper()
+	folder, err := ioutil.TempDir("", "labelmap")
+	if err != nil {
+		t.Fatalf("unable to create temporary folder: %v", err)
+	}
+	defer os.RemoveAll(folder)
+
+	filename := path.Join(folder, name)
+	err = ioutil.WriteFile(filename, []byte(contents), 0644)
+	if err != nil {
+		t.Fatalf("unable to write label map: %v", err)
+	}
+
+	return LoadLabelMap(filename)
+}
+
+func TestLoadLabelMap(t *testing.T) {
+	labels := []string{"Pastures", "Vineyards", "Sea and ocean", "Beaches, dunes, sands", "Olive groves"}
+	tests := []struct {
+		name     string
+		filename string
+		contents string
+		want     []string
+	}{
+		{
+			name:     "json",
+			filename: "map.json",
+			contents: `{"Vineyards": "Permanent crops", "Olive groves": "Permanent crops", "Sea and ocean": ""}`,
+			want:     []string{"Pastures", "Permanent crops", "Beaches, dunes, sands"},
+		},
+		{
+			name:     "csv with header",
+			filename: "map.csv",
+			contents: "old,new\nVineyards,Permanent crops\nOlive groves,Permanent crops\n\"Beaches, dunes, sands\",Beaches\n",
+			want:     []string{"Pastures", "Permanent crops", "Sea and ocean", "Beaches"},
+		},
+		{
+			name:     "csv without header",
+			filename: "map.CSV",
+			contents: "Pastures,Grassland\nSea and ocean,\n",
+			want:     []string{"Grassland", "Vineyards", "Beaches, dunes, sands", "Olive groves"},
+		},
+		{
+			name:     "csv dropping with a single column",
+			filename: "map.txt",
+			contents: "Pastures\nVineyards\n",
+			want:     []string{"Sea and ocean", "Beaches, dunes, sands", "Olive groves"},
+		},
+	}
+
+	for _, test := range tests {
+		labelMap, err := loadTestLabelMap(t, test.filename, test.contents)
+		if err != nil {
+			t.Fatalf("%s failed to load: %v", test.name, err)
+		}
+		if labelMap.Empty() {
+			t.Errorf("%s loaded an empty map", test.name)
+		}
+		if got := labelMap.Apply(labels); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s mapped labels to %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestLoadLabelMapErrors(t *testing.T) {
+	tests := []struct {
+		filename string
+		contents string
+	}{
+		{"map.json", `{"Vineyards": "Permanent crops"`},
+		{"map.json", `["Vineyards", "Permanent crops"]`},
+		{"map.csv", "Vineyards,Permanent crops,Agricultural areas\n"},
+		{"map.csv", "\"Vineyards,Permanent crops\n"},
+	}
+
+	for _, test := range tests {
+		_, err := loadTestLabelMap(t, test.filename, test.contents)
+		if err == nil {
+			t.Errorf("loading %s %q did not fail", test.filename, test.contents)
+		}
+	}
+
+	for _, filename := range []string{"missing.json", "missing.csv"} {
+		_, err := LoadLabelMap(filename)
+		if err == nil {
+			t.Errorf("loading %s did not fail", filename)
+		}
+	}
+}
+
+func TestEmptyLabelMap(t *testing.T) {
+	labelMap, err := LoadLabelMap("")
+	if err != nil {
+		t.Fatalf("LoadLabelMap failed: %v", err)
+	}
+	if !labelMap.Empty() {
+		t.Errorf("map without a file is not empty")
+	}
+
+	labels := []string{"Pastures", "Pastures", "Vineyards"}
+	if got := labelMap.Apply(labels); !reflect.DeepEqual(got, labels) {
+		t.Errorf("empty map changed labels to %v", got)
+	}
+}
+
+func TestLabelMapWriteRoundTrip(t *testing.T) {
+	labelMap, err := loadTestLabelMap(t, "map.csv", "Vineyards,Permanent crops\nSea and ocean,\n")
+	if err != nil {
+		t.Fatalf("LoadLabelMap failed: %v", err)
+	}
+
+	folder, err := ioutil.TempDir("", "labelmap")
+	if err != nil {
+		t.Fatalf("unable to create temporary folder: %v", err)
+	}
+	defer os.RemoveAll(folder)
+	filename := path.Join(folder, "written.json")
+	err = labelMap.Write(filename)
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	written, err := LoadLabelMap(filename)
+	if err != nil {
+		t.Fatalf("LoadLabelMap of the written map failed: %v", err)
+	}
+	want := [][2]string{{"Sea and ocean", ""}, {"Vineyards", "Permanent crops"}}
+	if got := written.Entries(); !reflect.DeepEqual(got, want) {
+		t.Errorf("written map has entries %v, want %v", got, want)
+	}
+}
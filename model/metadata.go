@@ -3,14 +3,45 @@ package model
 import (
 	"encoding/json"
 	"io/ioutil"
+	"time"
 
 	"github.com/pkg/errors"
 )
 
+const (
+	acquisitionDateFormat = "2006-01-02 15:04:05"
+	acquisitionTimeFormat = "2006-01-02T15:04:05"
+)
+
+// BoundingBox is the extent of a tile in the coordinates of its projection.
+type BoundingBox struct {
+	ULX float64 `json:"ulx"`
+	ULY float64 `json:"uly"`
+	LRX float64 `json:"lrx"`
+	LRY float64 `json:"lry"`
+}
+
 // TileMetadata is the metadata for one set of images from the BigEarth dataset.
+// Sentinel-1 patches of BigEarth-MM name their corresponding Sentinel-2 patch.
 type TileMetadata struct {
-	Filename string
-	Labels   []string `json:"labels"`
+	Filename             string
+	Labels               []string
+	Coordinates          *BoundingBox
+	Projection           string
+	TileSource           string
+	AcquisitionDate      time.Time
+	CorrespondingS2Patch string
+}
+
+type tileMetadataRaw struct {
+	Labels               []string     `json:"labels"`
+	Coordinates          *BoundingBox `json:"coordinates"`
+	Projection           string       `json:"projection"`
+	TileSource           string       `json:"tile_source"`
+	SceneSource          string       `json:"scene_source"`
+	AcquisitionDate      string       `json:"acquisition_date"`
+	AcquisitionTime      string       `json:"acquisition_time"`
+	CorrespondingS2Patch string       `json:"corresponding_s2_patch"`
 }
 
 func NewTileMetadata(filename string) *TileMetadata {
@@ -25,13 +56,44 @@ func (tm *TileMetadata) LoadMetadata() error {
 		return errors.Wrapf(err, "unable to read metadata from '%s'", tm.Filename)
 	}
 
-	var labels TileMetadata
-	err = json.Unmarshal(metadataRaw, &labels)
+	var raw tileMetadataRaw
+	err = json.Unmarshal(metadataRaw, &raw)
 	if err != nil {
 		return errors.Wrapf(err, "unable to unmarshal metadata from  '%s'", tm.Filename)
 	}
 
-	tm.Labels = labels.Labels
+	tm.Labels = raw.Labels
+	tm.Coordinates = raw.Coordinates
+	tm.Projection = raw.Projection
+	tm.TileSource = raw.TileSource
+	tm.CorrespondingS2Patch = raw.CorrespondingS2Patch
+
+	// sentinel-1 metadata uses different names for the source and date
+	if tm.TileSource == "" {
+		tm.TileSource = raw.SceneSource
+	}
+
+	if raw.AcquisitionDate != "" {
+		tm.AcquisitionDate, err = time.Parse(acquisitionDateFormat, raw.AcquisitionDate)
+		if err != nil {
+			return errors.Wrapf(err, "unable to parse acquisition date from '%s'", tm.Filename)
+		}
+	} else if raw.AcquisitionTime != "" {
+		tm.AcquisitionDate, err = time.Parse(acquisitionTimeFormat, raw.AcquisitionTime)
+		if err != nil {
+			return errors.Wrapf(err, "unable to parse acquisition time from '%s'", tm.Filename)
+		}
+	}
 
 	return nil
 }
+
+// Width returns the horizontal extent of the bounding box.
+func (b *BoundingBox) Width() float64 {
+	return b.LRX - b.ULX
+}
+
+// Height returns the vertical extent of the bounding box.
+func (b *BoundingBox) Height() float64 {
+	return b.ULY - b.LRY
+}
@@ -0,0 +1,178 @@
+package model
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// NomenclatureOriginalName is the name of the 43 class CORINE nomenclature.
+	NomenclatureOriginalName = "original"
+	// NomenclatureBigEarth19Name is the name of the 19 class BigEarthNet nomenclature.
+	NomenclatureBigEarth19Name = "bigearthnet-19"
+)
+
+var (
+	// OriginalLabels are the 43 CORINE Land Cover labels used by BigEarth.
+	OriginalLabels = []string{
+		"Continuous urban fabric",
+		"Discontinuous urban fabric",
+		"Industrial or commercial units",
+		"Road and rail networks and associated land",
+		"Port areas",
+		"Airports",
+		"Mineral extraction sites",
+		"Dump sites",
+		"Construction sites",
+		"Green urban areas",
+		"Sport and leisure facilities",
+		"Non-irrigated arable land",
+		"Permanently irrigated land",
+		"Rice fields",
+		"Vineyards",
+		"Fruit trees and berry plantations",
+		"Olive groves",
+		"Pastures",
+		"Annual crops associated with permanent crops",
+		"Complex cultivation patterns",
+		"Land principally occupied by agriculture, with significant areas of natural vegetation",
+		"Agro-forestry areas",
+		"Broad-leaved forest",
+		"Coniferous forest",
+		"Mixed forest",
+		"Natural grassland",
+		"Moors and heathland",
+		"Sclerophyllous vegetation",
+		"Transitional woodland/shrub",
+		"Beaches, dunes, sands",
+		"Bare rock",
+		"Sparsely vegetated areas",
+		"Burnt areas",
+		"Inland marshes",
+		"Peatbogs",
+		"Salt marshes",
+		"Salines",
+		"Intertidal flats",
+		"Water courses",
+		"Water bodies",
+		"Coastal lagoons",
+		"Estuaries",
+		"Sea and ocean",
+	}
+
+	// bigEarth19Conversion maps each of the 19 classes to the original labels
+	// it replaces. Original labels missing from the conversion have no class.
+	bigEarth19Conversion = []struct {
+		class    string
+		original []string
+	}{
+		{"Urban fabric", []string{"Continuous urban fabric", "Discontinuous urban fabric"}},
+		{"Industrial or commercial units", []string{"Industrial or commercial units"}},
+		{"Arable land", []string{"Non-irrigated arable land", "Permanently irrigated land", "Rice fields"}},
+		{"Permanent crops", []string{"Vineyards", "Fruit trees and berry plantations", "Olive groves", "Annual crops associated with permanent crops"}},
+		{"Pastures", []string{"Pastures"}},
+		{"Complex cultivation patterns", []string{"Complex cultivation patterns"}},
+		{"Land principally occupied by agriculture, with significant areas of natural vegetation", []string{"Land principally occupied by agriculture, with significant areas of natural vegetation"}},
+		{"Agro-forestry areas", []string{"Agro-forestry areas"}},
+		{"Broad-leaved forest", []string{"Broad-leaved forest"}},
+		{"Coniferous forest", []string{"Coniferous forest"}},
+		{"Mixed forest", []string{"Mixed forest"}},
+		{"Natural grassland and sparsely vegetated areas", []string{"Natural grassland", "Sparsely vegetated areas"}},
+		{"Moors, heathland and sclerophyllous vegetation", []string{"Moors and heathland", "Sclerophyllous vegetation"}},
+		{"Transitional woodland, shrub", []string{"Transitional woodland/shrub"}},
+		{"Beaches, dunes, sands", []string{"Beaches, dunes, sands"}},
+		{"Inland wetlands", []string{"Inland marshes", "Peatbogs"}},
+		{"Coastal wetlands", []string{"Salt marshes", "Salines"}},
+		{"Inland waters", []string{"Water courses", "Water bodies"}},
+		{"Marine waters", []string{"Coastal lagoons", "Estuaries", "Sea and ocean"}},
+	}
+)
+
+// Nomenclature is a set of land cover classes along with the conversion from
+// the original BigEarth labels. The original nomenclature keeps all labels
+// as is so that it can be used with datasets using other labels.
+type Nomenclature struct {
+	Name        string
+	Classes     []string
+	passThrough bool
+	conversion  map[string]string
+	classIndex  map[string]int
+}
+
+// NewOriginalNomenclature creates the nomenclature of the 43 original labels.
+func NewOriginalNomenclature() *Nomenclature {
+	n := newNomenclature(NomenclatureOriginalName)
+	n.passThrough = true
+	for _, label := range OriginalLabels {
+		n.addClass(label, []string{label})
+	}
+
+	return n
+}
+
+// NewBigEarth19Nomenclature creates the 19 class BigEarthNet nomenclature.
+func NewBigEarth19Nomenclature() *Nomenclature {
+	n := newNomenclature(NomenclatureBigEarth19Name)
+	for _, c := range bigEarth19Conversion {
+		n.addClass(c.class, c.original)
+	}
+
+	return n
+}
+
+// LookupNomenclature returns the nomenclature matching the name.
+func LookupNomenclature(name string) (*Nomenclature, error) {
+	switch strings.ToLower(name) {
+	case "", NomenclatureOriginalName, "43":
+		return NewOriginalNomenclature(), nil
+	case NomenclatureBigEarth19Name, "bigearth-19", "19":
+		return NewBigEarth19Nomenclature(), nil
+	case NomenclatureCorineLevelPrefix + "1":
+		return NewCorineNomenclature(1)
+	case NomenclatureCorineLevelPrefix + "2":
+		return NewCorineNomenclature(2)
+	case NomenclatureCorineLevelPrefix + "3":
+		return NewCorineNomenclature(3)
+	}
+
+	return nil, errors.Errorf("unknown label nomenclature '%s'", name)
+}
+
+func newNomenclature(name string) *Nomenclature {
+	return &Nomenclature{
+		Name:       name,
+		Classes:    make([]string, 0),
+		conversion: make(map[string]string),
+		classIndex: make(map[string]int),
+	}
+}
+
+func (n *Nomenclature) addClass(class string, original []string) {
+	n.classIndex[class] = len(n.Classes)
+	n.Classes = append(n.Classes, class)
+	for _, label := range original {
+		n.conversion[label] = class
+	}
+}
+
+// Convert returns the classes of the labels in the order they first appear.
+// Labels that are already classes of the nomenclature are kept while labels
+// without a class are dropped.
+func (n *Nomenclature) Convert(labels []string) []string {
+	classes := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, label := range labels {
+		class, ok := n.conversion[label]
+		if !ok {
+			class = label
+		}
+		_, known := n.classIndex[class]
+		if (known || n.passThrough) && !seen[class] {
+			seen[class] = true
+			classes = append(classes, class)
+		}
+	}
+
+	return classes
+}
@@ -0,0 +1,101 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBigEarth19Convert(t *testing.T) {
+	n := NewBigEarth19Nomenclature()
+	if len(n.Classes) != 19 {
+		t.Fatalf("BigEarthNet-19 has %d classes", len(n.Classes))
+	}
+
+	tests := []struct {
+		labels []string
+		want   []string
+	}{
+		{[]string{"Continuous urban fabric", "Discontinuous urban fabric"}, []string{"Urban fabric"}},
+		{[]string{"Sea and ocean", "Vineyards", "Estuaries"}, []string{"Marine waters", "Permanent crops"}},
+		{[]string{"Transitional woodland/shrub"}, []string{"Transitional woodland, shrub"}},
+		{[]string{"Natural grassland", "Sparsely vegetated areas"}, []string{"Natural grassland and sparsely vegetated areas"}},
+		{[]string{"Mixed forest", "Mixed forest"}, []string{"Mixed forest"}},
+		// classes of the nomenclature are kept as is
+		{[]string{"Inland wetlands", "Peatbogs"}, []string{"Inland wetlands"}},
+		// labels removed from the nomenclature are dropped
+		{[]string{"Airports", "Bare rock", "Burnt areas", "Intertidal flats", "Dump sites"}, []string{}},
+		{[]string{"Port areas", "Pastures", "Unknown label"}, []string{"Pastures"}},
+	}
+
+	for _, test := range tests {
+		got := n.Convert(test.labels)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Convert(%v) is %v, want %v", test.labels, got, test.want)
+		}
+	}
+}
+
+func TestBigEarth19CoversOriginalLabels(t *testing.T) {
+	dropped := map[string]bool{
+		"Road and rail networks and associated land": true,
+		"Port areas":                   true,
+		"Airports":                     true,
+		"Mineral extraction sites":     true,
+		"Dump sites":                   true,
+		"Construction sites":           true,
+		"Green urban areas":            true,
+		"Sport and leisure facilities": true,
+		"Bare rock":                    true,
+		"Burnt areas":                  true,
+		"Intertidal flats":             true,
+	}
+
+	n := NewBigEarth19Nomenclature()
+	for _, label := range OriginalLabels {
+		classes := n.Convert([]string{label})
+		if dropped[label] {
+			if len(classes) != 0 {
+				t.Errorf("dropped label %s converted to %v", label, classes)
+			}
+		} else if len(classes) != 1 {
+			t.Errorf("label %s converted to %v, want one class", label, classes)
+		}
+	}
+}
+
+func TestOriginalConvertPassesThrough(t *testing.T) {
+	n := NewOriginalNomenclature()
+	labels := []string{"Vineyards", "Custom label", "Vineyards", "Sea and ocean"}
+	want := []string{"Vineyards", "Custom label", "Sea and ocean"}
+	if got := n.Convert(labels); !reflect.DeepEqual(got, want) {
+		t.Errorf("Convert(%v) is %v, want %v", labels, got, want)
+	}
+}
+
+func TestLookupNomenclature(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"", NomenclatureOriginalName},
+		{"43", NomenclatureOriginalName},
+		{"BigEarthNet-19", NomenclatureBigEarth19Name},
+		{"bigearth-19", NomenclatureBigEarth19Name},
+		{"19", NomenclatureBigEarth19Name},
+		{"corine-level-2", "corine-level-2"},
+	}
+
+	for _, test := range tests {
+		n, err := LookupNomenclature(test.name)
+		if err != nil || n.Name != test.want {
+			t.Errorf("LookupNomenclature(%s) is %v (%v), want %s", test.name, n, err, test.want)
+		}
+	}
+
+	for _, name := range []string{"bigearthnet-10", "corine-level-4"} {
+		_, err := LookupNomenclature(name)
+		if err == nil {
+			t.Errorf("LookupNomenclature(%s) did not fail", name)
+		}
+	}
+}
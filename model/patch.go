@@ -0,0 +1,110 @@
+package model
+
+import (
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	patchTimestampFormat = "20060102T150405"
+)
+
+var (
+	satelliteRegex = regexp.MustCompile(`^S[12][A-D]$`)
+	gridTileRegex  = regexp.MustCompile(`^T?([0-9]{2}[A-Z]{3})$`)
+)
+
+// PatchID is the information encoded in the name of a BigEarth patch, such as
+// S2A_MSIL2A_20170613T101031_45_62 or S1A_IW_GRDH_1SDV_20170613T165043_33UUP_61_39,
+// which is the satellite, processing level and acquisition timestamp of the
+// scene followed by any other scene fields and the row and column of the
+// patch in the scene grid.
+type PatchID struct {
+	Name            string
+	Satellite       string
+	ProcessingLevel string
+	Timestamp       time.Time
+	GridTile        string
+	Row             int
+	Col             int
+}
+
+// ParsePatchID parses and validates a patch name.
+func ParsePatchID(name string) (*PatchID, error) {
+	fields := strings.Split(name, "_")
+	if len(fields) < 5 {
+		return nil, errors.Errorf("patch name '%s' does not have enough fields", name)
+	}
+	if !satelliteRegex.MatchString(fields[0]) {
+		return nil, errors.Errorf("patch name '%s' does not start with a Sentinel satellite", name)
+	}
+
+	// the timestamp separates the processing level from the other fields
+	timestampField := -1
+	var timestamp time.Time
+	for i := 2; i < len(fields)-2; i++ {
+		parsed, err := time.Parse(patchTimestampFormat, fields[i])
+		if err == nil {
+			timestampField = i
+			timestamp = parsed
+			break
+		}
+	}
+	if timestampField < 0 {
+		return nil, errors.Errorf("patch name '%s' does not have an acquisition timestamp", name)
+	}
+
+	row, err := strconv.Atoi(fields[len(fields)-2])
+	if err != nil || row < 0 {
+		return nil, errors.Errorf("patch name '%s' does not have a valid grid row", name)
+	}
+	col, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil || col < 0 {
+		return nil, errors.Errorf("patch name '%s' does not have a valid grid column", name)
+	}
+
+	id := &PatchID{
+		Name:            name,
+		Satellite:       fields[0],
+		ProcessingLevel: strings.Join(fields[1:timestampField], "_"),
+		Timestamp:       timestamp,
+		Row:             row,
+		Col:             col,
+	}
+	for _, field := range fields[timestampField+1 : len(fields)-2] {
+		match := gridTileRegex.FindStringSubmatch(field)
+		if match != nil {
+			id.GridTile = match[1]
+			break
+		}
+	}
+
+	return id, nil
+}
+
+// Sensor returns the sensor of the satellite.
+func (id *PatchID) Sensor() string {
+	if strings.HasPrefix(id.Satellite, "S1") {
+		return SensorSentinel1
+	}
+
+	return SensorSentinel2
+}
+
+// Scene returns the name of the scene the patch was cut from, which is the
+// patch name without the grid row and column.
+func (id *PatchID) Scene() string {
+	fields := strings.Split(id.Name, "_")
+
+	return strings.Join(fields[:len(fields)-2], "_")
+}
+
+// PatchID parses the patch name of the tile, ignoring any file extension.
+func (t *Tile) PatchID() (*PatchID, error) {
+	return ParsePatchID(strings.TrimSuffix(t.TileName, path.Ext(t.TileName)))
+}
@@ -0,0 +1,113 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePatchID(t *testing.T) {
+	tests := []struct {
+		name  string
+		want  PatchID
+		scene string
+	}{
+		{
+			name: "S2A_MSIL2A_20170613T101031_45_62",
+			want: PatchID{
+				Satellite:       "S2A",
+				ProcessingLevel: "MSIL2A",
+				Timestamp:       time.Date(2017, 6, 13, 10, 10, 31, 0, time.UTC),
+				Row:             45,
+				Col:             62,
+			},
+			scene: "S2A_MSIL2A_20170613T101031",
+		},
+		{
+			name: "S1A_IW_GRDH_1SDV_20170613T165043_33UUP_61_39",
+			want: PatchID{
+				Satellite:       "S1A",
+				ProcessingLevel: "IW_GRDH_1SDV",
+				Timestamp:       time.Date(2017, 6, 13, 16, 50, 43, 0, time.UTC),
+				GridTile:        "33UUP",
+				Row:             61,
+				Col:             39,
+			},
+			scene: "S1A_IW_GRDH_1SDV_20170613T165043_33UUP",
+		},
+		{
+			name: "S2B_MSIL2A_20180525T094031_N9999_R036_T35ULA_3_7",
+			want: PatchID{
+				Satellite:       "S2B",
+				ProcessingLevel: "MSIL2A",
+				Timestamp:       time.Date(2018, 5, 25, 9, 40, 31, 0, time.UTC),
+				GridTile:        "35ULA",
+				Row:             3,
+				Col:             7,
+			},
+			scene: "S2B_MSIL2A_20180525T094031_N9999_R036_T35ULA",
+		},
+	}
+
+	for _, test := range tests {
+		id, err := ParsePatchID(test.name)
+		if err != nil {
+			t.Errorf("ParsePatchID(%s) failed: %v", test.name, err)
+			continue
+		}
+
+		test.want.Name = test.name
+		if *id != test.want {
+			t.Errorf("ParsePatchID(%s) is %+v, want %+v", test.name, *id, test.want)
+		}
+		if id.Scene() != test.scene {
+			t.Errorf("scene of %s is %s, want %s", test.name, id.Scene(), test.scene)
+		}
+	}
+}
+
+func TestParsePatchIDErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"S2A_MSIL2A_45_62",
+		"L8_OLI_20170613T101031_45_62",
+		"S2A_MSIL2A_2017-06-13_45_62",
+		"S2A_MSIL2A_20170613T101031_45_x",
+		"S2A_MSIL2A_20170613T101031_-1_62",
+		"S2A_MSIL2A_20170613T101031_45",
+		"S2B_MSIL2A_20180204T94161_0_1",
+		"S2B_MSIL2A_20180525T94031_N9999_R036_T35ULA_3_7",
+	}
+
+	for _, name := range tests {
+		_, err := ParsePatchID(name)
+		if err == nil {
+			t.Errorf("ParsePatchID(%s) did not fail", name)
+		}
+	}
+}
+
+func TestPatchIDSensor(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"S2A_MSIL2A_20170613T101031_45_62", SensorSentinel2},
+		{"S1B_IW_GRDH_1SDV_20170613T165043_33UUP_61_39", SensorSentinel1},
+	}
+
+	for _, test := range tests {
+		id, err := ParsePatchID(test.name)
+		if err != nil {
+			t.Fatalf("ParsePatchID(%s) failed: %v", test.name, err)
+		}
+		if id.Sensor() != test.want {
+			t.Errorf("sensor of %s is %s, want %s", test.name, id.Sensor(), test.want)
+		}
+	}
+
+	tile := NewTile("source", "S2A_MSIL2A_20170613T101031_45_62.tif")
+	id, err := tile.PatchID()
+	if err != nil || id.Row != 45 || id.Col != 62 {
+		t.Errorf("tile patch id is %+v (%v), want row 45 and column 62", id, err)
+	}
+}
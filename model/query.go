@@ -0,0 +1,352 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// queryCountKeyword is the identifier of the number of labels of a tile.
+	queryCountKeyword = "labels"
+)
+
+// LabelQuery is a boolean expression over the labels of a tile, such as
+// "Pastures" AND NOT ("Coniferous forest" OR "Mixed forest"). Labels are
+// quoted, or bare if they are a single word, and compared ignoring case. The
+// number of labels is compared with predicates such as labels >= 3.
+type LabelQuery struct {
+	Source string
+	Labels []string
+	root   queryNode
+}
+
+type queryNode interface {
+	match(labels map[string]bool) bool
+}
+
+type queryLabelNode struct {
+	label string
+}
+
+type queryCountNode struct {
+	op    string
+	value int
+}
+
+type queryNotNode struct {
+	operand queryNode
+}
+
+type queryLogicalNode struct {
+	op    string
+	left  queryNode
+	right queryNode
+}
+
+type queryParser struct {
+	tokens    []expressionToken
+	current   int
+	known     map[string]string
+	labels    []string
+	labelSeen map[string]bool
+}
+
+// ParseLabelQuery parses a label query. Labels are resolved against the known
+// labels, if specified, so that misspelled labels are reported when parsing.
+func ParseLabelQuery(source string, knownLabels []string) (*LabelQuery, error) {
+	tokens, err := tokenizeQuery(source)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &queryParser{
+		tokens:    tokens,
+		labels:    make([]string, 0),
+		labelSeen: make(map[string]bool),
+	}
+	if knownLabels != nil {
+		parser.known = make(map[string]string)
+		for _, label := range knownLabels {
+			parser.known[normalizeLabel(label)] = label
+		}
+	}
+
+	root, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if parser.peek().kind != tokenEOF {
+		return nil, parser.errorf("unexpected '%s'", parser.peek().text)
+	}
+
+	return &LabelQuery{
+		Source: source,
+		Labels: parser.labels,
+		root:   root,
+	}, nil
+}
+
+// Match returns true if the labels satisfy the query.
+func (q *LabelQuery) Match(labels []string) bool {
+	set := make(map[string]bool)
+	for _, label := range labels {
+		set[normalizeLabel(label)] = true
+	}
+
+	return q.root.match(set)
+}
+
+func (n *queryLabelNode) match(labels map[string]bool) bool {
+	return labels[n.label]
+}
+
+func (n *queryCountNode) match(labels map[string]bool) bool {
+	count := len(labels)
+	switch n.op {
+	case ">":
+		return count > n.value
+	case ">=":
+		return count >= n.value
+	case "<":
+		return count < n.value
+	case "<=":
+		return count <= n.value
+	case "==":
+		return count == n.value
+	case "!=":
+		return count != n.value
+	}
+
+	return false
+}
+
+func (n *queryNotNode) match(labels map[string]bool) bool {
+	return !n.operand.match(labels)
+}
+
+func (n *queryLogicalNode) match(labels map[string]bool) bool {
+	if n.op == "&&" {
+		return n.left.match(labels) && n.right.match(labels)
+	}
+
+	return n.left.match(labels) || n.right.match(labels)
+}
+
+func normalizeLabel(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+func tokenizeQuery(source string) ([]expressionToken, error) {
+	tokens := make([]expressionToken, 0)
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := i
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '"' || r == '\'':
+			// quoted labels end at the matching quote, with backslash escapes
+			var label strings.Builder
+			i++
+			for i < len(runes) && runes[i] != r {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				label.WriteRune(runes[i])
+				i++
+			}
+			if i >= len(runes) {
+				return nil, errors.Errorf("unterminated label starting at position %d", start)
+			}
+			i++
+			tokens = append(tokens, expressionToken{tokenString, label.String(), start})
+		case unicode.IsDigit(r):
+			for i < len(runes) && unicode.IsDigit(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, expressionToken{tokenNumber, string(runes[start:i]), start})
+		case unicode.IsLetter(r) || r == '_':
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '-') {
+				i++
+			}
+			text := string(runes[start:i])
+			switch strings.ToLower(text) {
+			case "and":
+				tokens = append(tokens, expressionToken{tokenOperator, "&&", start})
+			case "or":
+				tokens = append(tokens, expressionToken{tokenOperator, "||", start})
+			case "not":
+				tokens = append(tokens, expressionToken{tokenOperator, "!", start})
+			default:
+				tokens = append(tokens, expressionToken{tokenIdentifier, text, start})
+			}
+		default:
+			op := ""
+			if i+1 < len(runes) {
+				switch string(runes[i : i+2]) {
+				case ">=", "<=", "==", "!=", "&&", "||":
+					op = string(runes[i : i+2])
+				}
+			}
+			if op == "" {
+				if !strings.ContainsRune("()<>!=", r) {
+					return nil, errors.Errorf("unexpected character '%c' at position %d", r, start)
+				}
+				op = string(r)
+			}
+			i += len(op)
+			if op == "=" {
+				op = "=="
+			}
+			tokens = append(tokens, expressionToken{tokenOperator, op, start})
+		}
+	}
+	tokens = append(tokens, expressionToken{tokenEOF, "end of query", len(runes)})
+
+	return tokens, nil
+}
+
+func (p *queryParser) peek() expressionToken {
+	return p.tokens[p.current]
+}
+
+func (p *queryParser) next() expressionToken {
+	token := p.tokens[p.current]
+	if token.kind != tokenEOF {
+		p.current++
+	}
+
+	return token
+}
+
+func (p *queryParser) accept(ops ...string) (string, bool) {
+	token := p.peek()
+	if token.kind != tokenOperator {
+		return "", false
+	}
+	for _, op := range ops {
+		if token.text == op {
+			p.current++
+			return op, true
+		}
+	}
+
+	return "", false
+}
+
+func (p *queryParser) errorf(format string, args ...interface{}) error {
+	return errors.Wrapf(errors.Errorf(format, args...), "invalid label query at position %d", p.peek().position)
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	return p.parseLogical(p.parseAnd, "||")
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	return p.parseLogical(p.parseNot, "&&")
+}
+
+func (p *queryParser) parseLogical(operand func() (queryNode, error), op string) (queryNode, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if _, ok := p.accept(op); !ok {
+			return left, nil
+		}
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = &queryLogicalNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *queryParser) parseNot() (queryNode, error) {
+	if _, ok := p.accept("!"); ok {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &queryNotNode{operand: operand}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (queryNode, error) {
+	token := p.peek()
+	switch token.kind {
+	case tokenString:
+		p.next()
+		return p.resolveLabel(token)
+	case tokenIdentifier:
+		if strings.EqualFold(token.text, queryCountKeyword) {
+			return p.parseCount()
+		}
+		p.next()
+		return p.resolveLabel(token)
+	case tokenOperator:
+		if token.text == "(" {
+			p.next()
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := p.accept(")"); !ok {
+				return nil, p.errorf("expected ')' but found '%s'", p.peek().text)
+			}
+			return node, nil
+		}
+	}
+
+	return nil, p.errorf("unexpected '%s'", token.text)
+}
+
+func (p *queryParser) parseCount() (queryNode, error) {
+	p.next()
+	op, ok := p.accept(">", ">=", "<", "<=", "==", "!=")
+	if !ok {
+		return nil, p.errorf("expected a comparison after '%s' but found '%s'", queryCountKeyword, p.peek().text)
+	}
+	token := p.peek()
+	if token.kind != tokenNumber {
+		return nil, p.errorf("expected a number of labels but found '%s'", token.text)
+	}
+	p.next()
+	value, err := strconv.Atoi(token.text)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid number '%s' at position %d", token.text, token.position)
+	}
+
+	return &queryCountNode{op: op, value: value}, nil
+}
+
+func (p *queryParser) resolveLabel(token expressionToken) (queryNode, error) {
+	key := normalizeLabel(token.text)
+	if key == "" {
+		return nil, errors.Errorf("empty label at position %d", token.position)
+	}
+
+	label := token.text
+	if p.known != nil {
+		known, ok := p.known[key]
+		if !ok {
+			return nil, errors.Errorf("unknown label '%s' at position %d", token.text, token.position)
+		}
+		label = known
+	}
+	if !p.labelSeen[key] {
+		p.labelSeen[key] = true
+		p.labels = append(p.labels, label)
+	}
+
+	return &queryLabelNode{label: key}, nil
+}
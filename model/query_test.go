@@ -0,0 +1,115 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLabelQueryMatch(t *testing.T) {
+	tiles := [][]string{
+		{"Pastures"},
+		{"Pastures", "Mixed forest"},
+		{"Pastures", "Sea and ocean", "Beaches, dunes, sands"},
+		{"Coniferous forest"},
+		{},
+	}
+	tests := []struct {
+		query string
+		want  []bool
+	}{
+		{`"Pastures" AND NOT ("Coniferous forest" OR "Mixed forest")`, []bool{true, false, true, false, false}},
+		{`labels >= 3`, []bool{false, false, true, false, false}},
+		{`labels > 1`, []bool{false, true, true, false, false}},
+		{`labels < 1`, []bool{false, false, false, false, true}},
+		{`labels <= 1`, []bool{true, false, false, true, true}},
+		{`labels == 2`, []bool{false, true, false, false, false}},
+		{`labels = 2`, []bool{false, true, false, false, false}},
+		{`labels != 1`, []bool{false, true, true, false, true}},
+		{`LABELS >= 1 and not pastures`, []bool{false, false, false, true, false}},
+		{`pastures and labels == 1`, []bool{true, false, false, false, false}},
+		{`'beaches, dunes, sands'`, []bool{false, false, true, false, false}},
+		{`"coniferous  FOREST"`, []bool{false, false, false, true, false}},
+		{`"Sea and ocean" or "Mixed forest" and "Coniferous forest"`, []bool{false, false, true, false, false}},
+		{`("Sea and ocean" or "Mixed forest") and "Pastures"`, []bool{false, true, true, false, false}},
+		{`not "Pastures" or "Mixed forest"`, []bool{false, true, false, true, true}},
+		{`not not "Pastures"`, []bool{true, true, true, false, false}},
+		{`!"Pastures" && labels > 0 || "Sea and ocean"`, []bool{false, false, true, true, false}},
+	}
+
+	for _, test := range tests {
+		query, err := ParseLabelQuery(test.query, OriginalLabels)
+		if err != nil {
+			t.Errorf("ParseLabelQuery(%q) failed: %v", test.query, err)
+			continue
+		}
+		for i, labels := range tiles {
+			if got := query.Match(labels); got != test.want[i] {
+				t.Errorf("ParseLabelQuery(%q).Match(%v) = %v, want %v", test.query, labels, got, test.want[i])
+			}
+		}
+	}
+}
+
+func TestLabelQueryLabels(t *testing.T) {
+	query, err := ParseLabelQuery(`pastures or ("mixed forest" and not Pastures)`, OriginalLabels)
+	if err != nil {
+		t.Fatalf("ParseLabelQuery failed: %v", err)
+	}
+
+	want := []string{"Pastures", "Mixed forest"}
+	if strings.Join(query.Labels, ",") != strings.Join(want, ",") {
+		t.Errorf("labels are %v, want the canonical labels %v", query.Labels, want)
+	}
+}
+
+func TestLabelQueryUnknownLabels(t *testing.T) {
+	query, err := ParseLabelQuery(`"say \"cheese\"" or 'it\'s' or water-body`, nil)
+	if err != nil {
+		t.Fatalf("ParseLabelQuery failed: %v", err)
+	}
+
+	want := []string{`say "cheese"`, "it's", "water-body"}
+	if strings.Join(query.Labels, "|") != strings.Join(want, "|") {
+		t.Errorf("labels are %v, want %v", query.Labels, want)
+	}
+	if !query.Match([]string{"It's"}) || query.Match([]string{"say cheese"}) {
+		t.Errorf("escaped labels not matched")
+	}
+}
+
+func TestLabelQueryErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{``, "invalid label query at position 0: unexpected 'end of query'"},
+		{`"Pastures`, "unterminated label starting at position 0"},
+		{`"Pastures" or 'Sea and ocean`, "unterminated label starting at position 14"},
+		{`"Pasture"`, "unknown label 'Pasture' at position 0"},
+		{`Pastures and Forest`, "unknown label 'Forest' at position 13"},
+		{`""`, "empty label at position 0"},
+		{`("Pastures"`, "invalid label query at position 11: expected ')' but found 'end of query'"},
+		{`"Pastures")`, "invalid label query at position 10: unexpected ')'"},
+		{`"Pastures" "Mixed forest"`, "invalid label query at position 11: unexpected 'Mixed forest'"},
+		{`"Pastures" and`, "invalid label query at position 14: unexpected 'end of query'"},
+		{`not`, "invalid label query at position 3: unexpected 'end of query'"},
+		{`labels`, "invalid label query at position 6: expected a comparison after 'labels' but found 'end of query'"},
+		{`labels >`, "invalid label query at position 8: expected a number of labels but found 'end of query'"},
+		{`labels > many`, "invalid label query at position 9: expected a number of labels but found 'many'"},
+		{`labels > 1 > 2`, "invalid label query at position 11: unexpected '>'"},
+		{`3 > labels`, "invalid label query at position 0: unexpected '3'"},
+		{`Pastures & Forest`, "unexpected character '&' at position 9"},
+		{`Pastures + 1`, "unexpected character '+' at position 9"},
+	}
+
+	for _, test := range tests {
+		_, err := ParseLabelQuery(test.query, OriginalLabels)
+		if err == nil {
+			t.Errorf("ParseLabelQuery(%q) succeeded, want error %q", test.query, test.want)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.want) {
+			t.Errorf("ParseLabelQuery(%q) error is %q, want %q", test.query, err.Error(), test.want)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package model
+
+import (
+	"math"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Resample returns a copy of the image resampled to the specified size using
+// nearest neighbour interpolation. The data type is preserved and the
+// geotransform is scaled to keep the footprint of the image.
+func (i *Image) Resample(sizeX int, sizeY int) *Image {
+	resampled := &Image{
+		Band:         i.Band,
+		Filename:     i.Filename,
+		BandIndex:    i.BandIndex,
+		SizeX:        sizeX,
+		SizeY:        sizeY,
+		GeoTransform: scaleGeoTransform(i.GeoTransform, float64(i.SizeX)/float64(sizeX), float64(i.SizeY)/float64(sizeY)),
+		Projection:   i.Projection,
+		NoData:       i.NoData,
+		HasNoData:    i.HasNoData,
+		DataType:     i.DataType,
+	}
+	resampled.allocatePixels()
+
+	// nearest neighbour values are source values, so converting back to the
+	// data type is lossless once nodata is restored
+	for y, row := range i.ResampleValues(sizeX, sizeY, ResampleNearest) {
+		for x, v := range row {
+			value := float64(v)
+			if i.HasNoData && math.IsNaN(value) {
+				value = i.NoData
+			}
+			resampled.setValue(y*sizeX+x, value)
+		}
+	}
+
+	return resampled
+}
+
+func scaleGeoTransform(geoTransform [6]float64, scaleX float64, scaleY float64) [6]float64 {
+	geoTransform[1] = geoTransform[1] * scaleX
+	geoTransform[4] = geoTransform[4] * scaleX
+	geoTransform[2] = geoTransform[2] * scaleY
+	geoTransform[5] = geoTransform[5] * scaleY
+
+	return geoTransform
+}
+
+// ResampleMethod is the interpolation used when resampling an image.
+type ResampleMethod int
+
+const (
+	// ResampleNearest uses the value of the closest source pixel.
+	ResampleNearest ResampleMethod = iota
+	// ResampleBilinear interpolates linearly between the 4 closest source pixels.
+	ResampleBilinear
+	// ResampleCubic uses cubic convolution over the 16 closest source pixels.
+	ResampleCubic
+)
+
+// ParseResampleMethod returns the resample method matching the name.
+func ParseResampleMethod(name string) (ResampleMethod, error) {
+	switch strings.ToLower(name) {
+	case "nearest", "":
+		return ResampleNearest, nil
+	case "bilinear":
+		return ResampleBilinear, nil
+	case "cubic":
+		return ResampleCubic, nil
+	}
+
+	return ResampleNearest, errors.Errorf("unknown resample method '%s'", name)
+}
+
+// ResampleValues resamples the image to the specified size, returning the
+// values as rows of float32. Nodata pixels are left out of the interpolation,
+// and output pixels without any valid source pixel are NaN.
+func (i *Image) ResampleValues(sizeX int, sizeY int, method ResampleMethod) [][]float32 {
+	source := i.Float64Pixels()
+	if i.HasNoData {
+		for p := range source {
+			if i.IsNoData(p) {
+				source[p] = math.NaN()
+			}
+		}
+	}
+	scaleX := float64(i.SizeX) / float64(sizeX)
+	scaleY := float64(i.SizeY) / float64(sizeY)
+
+	// sample at the center of the output pixels, working in source pixel
+	// coordinates where integer values are the centers of the source pixels
+	rows := make([][]float32, sizeY)
+	for y := 0; y < sizeY; y++ {
+		rows[y] = make([]float32, sizeX)
+		sy := (float64(y)+0.5)*scaleY - 0.5
+		for x := 0; x < sizeX; x++ {
+			sx := (float64(x)+0.5)*scaleX - 0.5
+
+			var value float64
+			switch method {
+			case ResampleBilinear:
+				value = i.sampleBilinear(source, sx, sy)
+			case ResampleCubic:
+				value = i.sampleCubic(source, sx, sy)
+			default:
+				value = source[i.clampY(int(math.Round(sy)))*i.SizeX+i.clampX(int(math.Round(sx)))]
+			}
+			rows[y][x] = float32(value)
+		}
+	}
+
+	return rows
+}
+
+// sampleBilinear interpolates the valid pixels among the 4 closest source
+// pixels, renormalising the weights to exclude nodata (NaN) pixels.
+func (i *Image) sampleBilinear(source []float64, sx float64, sy float64) float64 {
+	x0 := math.Floor(sx)
+	y0 := math.Floor(sy)
+	fx := sx - x0
+	fy := sy - y0
+
+	left := i.clampX(int(x0))
+	right := i.clampX(int(x0) + 1)
+	top := i.clampY(int(y0))
+	bottom := i.clampY(int(y0) + 1)
+
+	value := 0.0
+	weight := 0.0
+	for _, s := range []struct {
+		index  int
+		weight float64
+	}{
+		{top*i.SizeX + left, (1 - fx) * (1 - fy)},
+		{top*i.SizeX + right, fx * (1 - fy)},
+		{bottom*i.SizeX + left, (1 - fx) * fy},
+		{bottom*i.SizeX + right, fx * fy},
+	} {
+		if s.weight > 0 && !math.IsNaN(source[s.index]) {
+			value += source[s.index] * s.weight
+			weight += s.weight
+		}
+	}
+	if weight == 0 {
+		return math.NaN()
+	}
+
+	return value / weight
+}
+
+// sampleCubic convolves the 16 closest source pixels. The negative lobes of
+// the kernel make renormalising over a partial neighbourhood unstable, so
+// pixels next to nodata fall back to bilinear interpolation.
+func (i *Image) sampleCubic(source []float64, sx float64, sy float64) float64 {
+	x0 := math.Floor(sx)
+	y0 := math.Floor(sy)
+	fx := sx - x0
+	fy := sy - y0
+
+	value := 0.0
+	for m := -1; m <= 2; m++ {
+		row := i.clampY(int(y0)+m) * i.SizeX
+		wy := cubicWeight(float64(m) - fy)
+		for n := -1; n <= 2; n++ {
+			w := wy * cubicWeight(float64(n)-fx)
+			if w == 0 {
+				continue
+			}
+			sample := source[row+i.clampX(int(x0)+n)]
+			if math.IsNaN(sample) {
+				return i.sampleBilinear(source, sx, sy)
+			}
+			value += sample * w
+		}
+	}
+
+	return value
+}
+
+// cubicWeight is the Keys cubic convolution kernel with a = -0.5.
+func cubicWeight(d float64) float64 {
+	a := -0.5
+	d = math.Abs(d)
+	if d <= 1 {
+		return (a+2)*d*d*d - (a+3)*d*d + 1
+	} else if d < 2 {
+		return a*d*d*d - 5*a*d*d + 8*a*d - 4*a
+	}
+
+	return 0
+}
+
+func (i *Image) clampX(x int) int {
+	return clamp(x, i.SizeX-1)
+}
+
+func (i *Image) clampY(y int) int {
+	return clamp(y, i.SizeY-1)
+}
+
+func clamp(value int, max int) int {
+	if value < 0 {
+		return 0
+	} else if value > max {
+		return max
+	}
+
+	return value
+}
@@ -0,0 +1,160 @@
+package model
+
+import (
+	"math"
+	"testing"
+)
+
+// newTestImage creates a float32 image with the values given row by row.
+func newTestImage(sizeX int, sizeY int, values []float64) *Image {
+	img := &Image{
+		Band:          "02",
+		SizeX:         sizeX,
+		SizeY:         sizeY,
+		GeoTransform:  [6]float64{0, 10, 0, 0, 0, -10},
+		DataType:      DataTypeFloat32,
+		PixelsFloat32: make([]float32, len(values)),
+	}
+	for p, v := range values {
+		img.PixelsFloat32[p] = float32(v)
+	}
+
+	return img
+}
+
+// rampImage creates an image whose values are the column of the pixel.
+func rampImage(sizeX int, sizeY int) *Image {
+	values := make([]float64, sizeX*sizeY)
+	for p := range values {
+		values[p] = float64(p % sizeX)
+	}
+
+	return newTestImage(sizeX, sizeY, values)
+}
+
+func TestResampleMatchesNearestValues(t *testing.T) {
+	img := &Image{
+		SizeX:        5,
+		SizeY:        3,
+		GeoTransform: [6]float64{100, 60, 0, 200, 0, -60},
+		NoData:       0,
+		HasNoData:    true,
+		DataType:     DataTypeUint16,
+		PixelsUint16: []uint16{1, 2, 3, 4, 5, 6, 0, 8, 9, 10, 11, 12, 13, 14, 15},
+	}
+
+	for _, size := range [][2]int{{10, 6}, {15, 9}, {2, 1}, {3, 2}} {
+		resampled := img.Resample(size[0], size[1])
+		if resampled.DataType != DataTypeUint16 || len(resampled.PixelsUint16) != size[0]*size[1] {
+			t.Fatalf("resampled to %v as %s with %d pixels", size, resampled.DataType, len(resampled.PixelsUint16))
+		}
+
+		// the image and the cube share one nearest neighbour grid
+		values := img.ResampleValues(size[0], size[1], ResampleNearest)
+		for y, row := range values {
+			for x, v := range row {
+				got := resampled.PixelsUint16[y*size[0]+x]
+				if math.IsNaN(float64(v)) {
+					if got != 0 {
+						t.Errorf("%v pixel (%d, %d) is %d, want nodata", size, x, y, got)
+					}
+				} else if float32(got) != v {
+					t.Errorf("%v pixel (%d, %d) is %d, want %v", size, x, y, got, v)
+				}
+			}
+		}
+	}
+
+	resampled := img.Resample(10, 6)
+	if resampled.GeoTransform[1] != 30 || resampled.GeoTransform[5] != -30 || resampled.GeoTransform[0] != 100 {
+		t.Errorf("resampled geotransform %v does not keep the footprint", resampled.GeoTransform)
+	}
+}
+
+func TestResampleValuesLinearRamp(t *testing.T) {
+	img := rampImage(8, 4)
+
+	for _, method := range []ResampleMethod{ResampleBilinear, ResampleCubic} {
+		rows := img.ResampleValues(16, 8, method)
+		for y, row := range rows {
+			// away from the clamped edges both kernels reproduce a linear ramp
+			for x := 4; x < 12; x++ {
+				want := (float64(x)+0.5)/2 - 0.5
+				if math.Abs(float64(row[x])-want) > 1e-5 {
+					t.Errorf("method %d pixel (%d, %d) is %v, want %v", method, x, y, row[x], want)
+				}
+			}
+		}
+	}
+}
+
+func TestResampleValuesKernels(t *testing.T) {
+	img := newTestImage(2, 2, []float64{0, 10, 20, 30})
+
+	// downsampling to a single pixel samples the center of the image
+	tests := []struct {
+		method ResampleMethod
+		want   float64
+	}{
+		{ResampleNearest, 30},
+		{ResampleBilinear, 15},
+		{ResampleCubic, 15},
+	}
+	for _, test := range tests {
+		got := img.ResampleValues(1, 1, test.method)[0][0]
+		if math.Abs(float64(got)-test.want) > 1e-5 {
+			t.Errorf("method %d sampled %v, want %v", test.method, got, test.want)
+		}
+	}
+
+	// cubic convolution overshoots at a step while bilinear does not
+	step := newTestImage(4, 1, []float64{0, 0, 10, 10})
+	bilinear := step.ResampleValues(8, 1, ResampleBilinear)[0]
+	cubic := step.ResampleValues(8, 1, ResampleCubic)[0]
+	for x := range bilinear {
+		if bilinear[x] < 0 || bilinear[x] > 10 {
+			t.Errorf("bilinear pixel %d is %v, outside the source range", x, bilinear[x])
+		}
+	}
+	if cubic[2] >= 0 || cubic[5] <= 10 {
+		t.Errorf("cubic step %v does not overshoot", cubic)
+	}
+}
+
+func TestResampleValuesNoData(t *testing.T) {
+	img := newTestImage(4, 4, []float64{
+		-1, -1, 10, 10,
+		-1, -1, 10, 10,
+		10, 10, 10, 10,
+		10, 10, 10, 10,
+	})
+	img.NoData = -1
+	img.HasNoData = true
+
+	for _, method := range []ResampleMethod{ResampleNearest, ResampleBilinear, ResampleCubic} {
+		rows := img.ResampleValues(8, 8, method)
+		// output pixels inside the nodata block have no valid source pixel
+		if !math.IsNaN(float64(rows[0][0])) {
+			t.Errorf("method %d nodata corner is %v, want NaN", method, rows[0][0])
+		}
+		for y, row := range rows {
+			for x, v := range row {
+				if !math.IsNaN(float64(v)) && math.Abs(float64(v)-10) > 1e-5 {
+					t.Errorf("method %d pixel (%d, %d) is %v, blending in nodata", method, x, y, v)
+				}
+			}
+		}
+		if rows[7][7] != 10 {
+			t.Errorf("method %d valid corner is %v, want 10", method, rows[7][7])
+		}
+	}
+
+	// NaN nodata is recognised as well
+	nan := newTestImage(2, 1, []float64{math.NaN(), 4})
+	nan.NoData = math.NaN()
+	nan.HasNoData = true
+	row := nan.ResampleValues(4, 1, ResampleBilinear)[0]
+	if !math.IsNaN(float64(row[0])) || row[2] != 4 || row[3] != 4 {
+		t.Errorf("NaN nodata resampled to %v", row)
+	}
+}
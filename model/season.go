@@ -0,0 +1,106 @@
+package model
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// SeasonWinter is December to February in the northern hemisphere.
+	SeasonWinter = "winter"
+	// SeasonSpring is March to May in the northern hemisphere.
+	SeasonSpring = "spring"
+	// SeasonSummer is June to August in the northern hemisphere.
+	SeasonSummer = "summer"
+	// SeasonAutumn is September to November in the northern hemisphere.
+	SeasonAutumn = "autumn"
+)
+
+var (
+	// Seasons lists the seasons in calendar order.
+	Seasons = []string{SeasonWinter, SeasonSpring, SeasonSummer, SeasonAutumn}
+
+	utmZoneRegex = regexp.MustCompile(`(?i)UTM zone [0-9]+([NS])`)
+	utmEPSGRegex = regexp.MustCompile(`EPSG"?[,:]\s*"?32([67])[0-9]{2}\b`)
+)
+
+// ParseSeason validates a season name, accepting fall for autumn.
+func ParseSeason(season string) (string, error) {
+	season = strings.ToLower(strings.TrimSpace(season))
+	if season == "fall" {
+		season = SeasonAutumn
+	}
+	for _, s := range Seasons {
+		if s == season {
+			return s, nil
+		}
+	}
+
+	return "", errors.Errorf("unknown season '%s' (expected winter, spring, summer or autumn)", season)
+}
+
+// SeasonOf returns the meteorological season of the date, with the seasons
+// of the southern hemisphere being opposite those of the northern hemisphere.
+func SeasonOf(date time.Time, southern bool) string {
+	index := (int(date.Month()) % 12) / 3
+	if southern {
+		index = (index + 2) % 4
+	}
+
+	return Seasons[index]
+}
+
+// AcquisitionDate returns the acquisition date of the tile from its metadata,
+// or from its patch name if the metadata has no date.
+func (t *Tile) AcquisitionDate() (time.Time, bool) {
+	if t.Metadata != nil && !t.Metadata.AcquisitionDate.IsZero() {
+		return t.Metadata.AcquisitionDate, true
+	}
+
+	id, err := t.PatchID()
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return id.Timestamp, true
+}
+
+// Satellite returns the satellite, such as S2A, that captured the tile from
+// its patch name or the tile source in its metadata.
+func (t *Tile) Satellite() (string, bool) {
+	id, err := t.PatchID()
+	if err == nil {
+		return id.Satellite, true
+	}
+
+	if t.Metadata != nil && t.Metadata.TileSource != "" {
+		satellite := strings.Split(t.Metadata.TileSource, "_")[0]
+		if satelliteRegex.MatchString(satellite) {
+			return satellite, true
+		}
+	}
+
+	return "", false
+}
+
+// Southern returns true if the projection of the tile is a southern UTM zone,
+// and false if the hemisphere cannot be determined from the projection.
+func (t *Tile) Southern() (bool, bool) {
+	if t.Metadata == nil || t.Metadata.Projection == "" {
+		return false, false
+	}
+
+	match := utmZoneRegex.FindStringSubmatch(t.Metadata.Projection)
+	if match != nil {
+		return strings.ToUpper(match[1]) == "S", true
+	}
+	match = utmEPSGRegex.FindStringSubmatch(t.Metadata.Projection)
+	if match != nil {
+		return match[1] == "7", true
+	}
+
+	return false, false
+}
@@ -0,0 +1,131 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+const (
+	utm33NorthWKT = `PROJCS["WGS 84 / UTM zone 33N",GEOGCS["WGS 84"],AUTHORITY["EPSG","32633"]]`
+	utm35SouthWKT = `PROJCS["WGS 84 / UTM zone 35S",GEOGCS["WGS 84"],AUTHORITY["EPSG","32735"]]`
+	epsgSouthWKT  = `PROJCS["unnamed",GEOGCS["WGS 84"],AUTHORITY["EPSG","32735"]]`
+)
+
+func TestParseSeason(t *testing.T) {
+	tests := []struct {
+		season string
+		want   string
+	}{
+		{"winter", SeasonWinter},
+		{" Spring ", SeasonSpring},
+		{"SUMMER", SeasonSummer},
+		{"autumn", SeasonAutumn},
+		{"fall", SeasonAutumn},
+		{"monsoon", ""},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		got, err := ParseSeason(test.season)
+		if test.want == "" {
+			if err == nil {
+				t.Errorf("ParseSeason(%s) did not fail", test.season)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("ParseSeason(%s) is %s (%v), want %s", test.season, got, err, test.want)
+		}
+	}
+}
+
+func TestSeasonOf(t *testing.T) {
+	north := []string{
+		SeasonWinter, SeasonWinter, SeasonSpring, SeasonSpring, SeasonSpring, SeasonSummer,
+		SeasonSummer, SeasonSummer, SeasonAutumn, SeasonAutumn, SeasonAutumn, SeasonWinter,
+	}
+	south := map[string]string{
+		SeasonWinter: SeasonSummer,
+		SeasonSpring: SeasonAutumn,
+		SeasonSummer: SeasonWinter,
+		SeasonAutumn: SeasonSpring,
+	}
+
+	for m, want := range north {
+		date := time.Date(2017, time.Month(m+1), 15, 10, 0, 0, 0, time.UTC)
+		if got := SeasonOf(date, false); got != want {
+			t.Errorf("northern season of %s is %s, want %s", date.Month(), got, want)
+		}
+		if got := SeasonOf(date, true); got != south[want] {
+			t.Errorf("southern season of %s is %s, want %s", date.Month(), got, south[want])
+		}
+	}
+}
+
+func TestTileSouthern(t *testing.T) {
+	tests := []struct {
+		projection string
+		southern   bool
+		known      bool
+	}{
+		{utm33NorthWKT, false, true},
+		{utm35SouthWKT, true, true},
+		{epsgSouthWKT, true, true},
+		{`GEOGCS["WGS 84",AUTHORITY["EPSG","4326"]]`, false, false},
+		{"", false, false},
+	}
+
+	for _, test := range tests {
+		tile := NewTile("source", "S2A_MSIL2A_20170613T101031_45_62")
+		tile.Metadata = &TileMetadata{Projection: test.projection}
+		southern, known := tile.Southern()
+		if southern != test.southern || known != test.known {
+			t.Errorf("projection %s southern is %v (known %v), want %v (known %v)", test.projection, southern, known, test.southern, test.known)
+		}
+	}
+}
+
+func TestTileAcquisitionDateAndSatellite(t *testing.T) {
+	metadataDate := time.Date(2018, 2, 4, 9, 41, 1, 0, time.UTC)
+	tests := []struct {
+		name      string
+		metadata  *TileMetadata
+		date      time.Time
+		satellite string
+	}{
+		{
+			name:      "S2A_MSIL2A_20170613T101031_45_62",
+			date:      time.Date(2017, 6, 13, 10, 10, 31, 0, time.UTC),
+			satellite: "S2A",
+		},
+		{
+			name:      "S2B_MSIL2A_20170613T101031_45_62",
+			metadata:  &TileMetadata{AcquisitionDate: metadataDate},
+			date:      metadataDate,
+			satellite: "S2B",
+		},
+		{
+			name:      "patch_45_62",
+			metadata:  &TileMetadata{AcquisitionDate: metadataDate, TileSource: "S1B_IW_GRDH_1SDV_20180204T094101"},
+			date:      metadataDate,
+			satellite: "S1B",
+		},
+		{
+			name:     "patch_45_62",
+			metadata: &TileMetadata{TileSource: "LC08_L1TP"},
+		},
+	}
+
+	for _, test := range tests {
+		tile := NewTile("source", test.name)
+		tile.Metadata = test.metadata
+		date, ok := tile.AcquisitionDate()
+		if ok != !test.date.IsZero() || !date.Equal(test.date) {
+			t.Errorf("%s acquisition date is %v (%v), want %v", test.name, date, ok, test.date)
+		}
+		satellite, ok := tile.Satellite()
+		if ok != (test.satellite != "") || satellite != test.satellite {
+			t.Errorf("%s satellite is %s (%v), want %s", test.name, satellite, ok, test.satellite)
+		}
+	}
+}
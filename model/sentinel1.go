@@ -0,0 +1,69 @@
+package model
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Sensor returns the sensor that captured the tile, based on the patch name
+// or the bands of the loaded images.
+func (t *Tile) Sensor() string {
+	id, err := t.PatchID()
+	if err == nil {
+		return id.Sensor()
+	}
+
+	for _, img := range t.Images {
+		info, ok := img.BandInfo()
+		if ok {
+			return info.Sensor
+		}
+	}
+
+	return ""
+}
+
+// ExpectedBands returns the bands a BigEarth patch captured by the sensor of
+// the tile should have, including the bands of the paired tile.
+func (t *Tile) ExpectedBands() []string {
+	bands := make([]string, 0)
+	switch t.Sensor() {
+	case SensorSentinel1:
+		bands = append(bands, BigEarthS1Bands...)
+	case SensorSentinel2:
+		bands = append(bands, BigEarthS2Bands...)
+	}
+	if t.Paired != nil {
+		bands = append(bands, t.Paired.ExpectedBands()...)
+	}
+
+	return bands
+}
+
+// PairWithS2 pairs a Sentinel-1 tile with the Sentinel-2 patch named in its
+// metadata, found in the specified folder. The tile metadata must be loaded.
+func (t *Tile) PairWithS2(s2Folder string) error {
+	if t.Metadata == nil {
+		return errors.Errorf("no metadata loaded for tile '%s'", t.TileName)
+	}
+	if t.Metadata.CorrespondingS2Patch == "" {
+		return errors.Errorf("no corresponding Sentinel-2 patch in the metadata of tile '%s'", t.TileName)
+	}
+
+	t.Paired = NewTile(s2Folder, t.Metadata.CorrespondingS2Patch)
+
+	return nil
+}
+
+func (t *Tile) loadPairedImages() error {
+	if t.Paired == nil {
+		return nil
+	}
+
+	err := t.Paired.LoadImages()
+	if err != nil {
+		return errors.Wrapf(err, "unable to load tile '%s' paired with '%s'", t.Paired.TileName, t.TileName)
+	}
+	t.Images = append(t.Images, t.Paired.Images...)
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package model
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// StackBands writes the bands of the tile into a single multiband GeoTIFF in
+// the specified band order, or ordered by band name if no order is given.
+// Bands are resampled to the grid of the finest resolution band and the tile
+// metadata is stored in the GeoTIFF metadata.
+func (t *Tile) StackBands(outputFilename string, bandOrder []string) error {
+	if len(t.Images) == 0 {
+		err := t.LoadFiles()
+		if err != nil {
+			return err
+		}
+	}
+
+	images, err := t.selectBands(bandOrder)
+	if err != nil {
+		return err
+	}
+
+	// use the largest band as the common grid
+	sizeX := 0
+	sizeY := 0
+	for _, img := range images {
+		if img.SizeX*img.SizeY > sizeX*sizeY {
+			sizeX = img.SizeX
+			sizeY = img.SizeY
+		}
+	}
+
+	aligned := make([]*Image, len(images))
+	for i, img := range images {
+		if img.SizeX != sizeX || img.SizeY != sizeY {
+			img = img.Resample(sizeX, sizeY)
+		}
+		aligned[i] = img
+	}
+
+	metadata, err := t.geoTIFFMetadata()
+	if err != nil {
+		return err
+	}
+
+	err = WriteGeoTIFF(outputFilename, aligned, metadata)
+	if err != nil {
+		return errors.Wrapf(err, "unable to write stacked bands of '%s'", t.TileName)
+	}
+
+	return nil
+}
+
+// GetImage returns the image for the specified band, or nil if the tile does
+// not have that band.
+func (t *Tile) GetImage(band string) *Image {
+	band = normalizeBandName(band)
+	for _, img := range t.Images {
+		if normalizeBandName(img.Band) == band {
+			return img
+		}
+	}
+
+	return nil
+}
+
+func (t *Tile) selectBands(bandOrder []string) ([]*Image, error) {
+	if len(bandOrder) == 0 {
+		images := make([]*Image, len(t.Images))
+		copy(images, t.Images)
+		sort.Slice(images, func(i int, j int) bool {
+			return images[i].Band < images[j].Band
+		})
+
+		return images, nil
+	}
+
+	images := make([]*Image, 0, len(bandOrder))
+	for _, band := range bandOrder {
+		img := t.GetImage(band)
+		if img == nil {
+			return nil, errors.Errorf("band '%s' not found in tile '%s'", band, t.TileName)
+		}
+		images = append(images, img)
+	}
+
+	return images, nil
+}
+
+func (t *Tile) geoTIFFMetadata() (map[string]string, error) {
+	metadata := make(map[string]string)
+	if t.Metadata == nil {
+		return metadata, nil
+	}
+
+	labels, err := json.Marshal(t.Metadata.Labels)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to marshal labels")
+	}
+	metadata["labels"] = string(labels)
+
+	if t.Metadata.TileSource != "" {
+		metadata["tile_source"] = t.Metadata.TileSource
+	}
+	if !t.Metadata.AcquisitionDate.IsZero() {
+		metadata["acquisition_date"] = t.Metadata.AcquisitionDate.Format(acquisitionDateFormat)
+	}
+
+	return metadata, nil
+}
@@ -1,39 +1,34 @@
 package model
 
 import (
-	"bytes"
 	"fmt"
-	"image"
 	"io/ioutil"
 	"os"
 	"path"
 	"regexp"
 	"strings"
 
-	"golang.org/x/image/tiff"
-
 	"github.com/pkg/errors"
 	"github.com/uncharted-distil/gdal"
 )
 
 var (
-	bandRegex = regexp.MustCompile(`_B[0-9][0-9a-zA-Z][.]`)
+	bandRegex        = regexp.MustCompile(`_B[0-9][0-9a-zA-Z][.]`)
+	s1BandRegex      = regexp.MustCompile(`_(VV|VH)[.]`)
+	derivedBandRegex = regexp.MustCompile(`_([A-Za-z][A-Za-z0-9]*)[.][A-Za-z]+$`)
 )
 
+// Tile is a set of images captured over the same area. A paired tile, such as
+// the Sentinel-2 patch of a Sentinel-1 patch, is loaded with the tile and
+// its images are added to the images of the tile.
 type Tile struct {
-	BaseFolder string
-	TileName   string
-	Images     []*Image
-	Metadata   *TileMetadata
-	MultiBand  bool
-}
-
-type Image struct {
-	Band     string
-	Filename string
-	SizeX    int
-	SizeY    int
-	Pixels   []uint16
+	BaseFolder  string
+	TileName    string
+	Images      []*Image
+	Metadata    *TileMetadata
+	MultiBand   bool
+	BandMapping map[int]string
+	Paired      *Tile
 }
 
 func NewTile(baseFolder string, tileName string) *Tile {
@@ -52,15 +47,6 @@ func NewTileMultiBand(imageName string) *Tile {
 	}
 }
 
-func NewImage(filename string) *Image {
-	band := extractBand(filename)
-
-	return &Image{
-		Band:     band,
-		Filename: filename,
-	}
-}
-
 func (t *Tile) LoadMetadata() error {
 	// read the folder from the tile name
 	tileFolder := t.GetCompletePath()
@@ -76,7 +62,10 @@ func (t *Tile) LoadMetadata() error {
 	for _, f := range imageFiles {
 		if path.Ext(f.Name()) == ".json" {
 			t.Metadata = NewTileMetadata(path.Join(tileFolder, f.Name()))
-			t.Metadata.LoadMetadata()
+			err = t.Metadata.LoadMetadata()
+			if err != nil {
+				return err
+			}
 			break
 		}
 	}
@@ -103,7 +92,10 @@ func (t *Tile) LoadFiles() error {
 	for _, f := range imageFiles {
 		if path.Ext(f.Name()) == ".json" {
 			t.Metadata = NewTileMetadata(path.Join(tileFolder, f.Name()))
-			t.Metadata.LoadMetadata()
+			err = t.Metadata.LoadMetadata()
+			if err != nil {
+				return err
+			}
 		} else {
 			imagePath := path.Join(tileFolder, f.Name())
 			img := NewImage(imagePath)
@@ -116,59 +108,29 @@ func (t *Tile) LoadFiles() error {
 		}
 	}
 
-	return nil
+	return t.loadPairedImages()
 }
 
 func (t *Tile) LoadImages() error {
+	var err error
 	if t.MultiBand {
-		return t.loadMultiBandImage()
-	}
-
-	return t.loadSingleBandImages()
-}
-
-func (t *Tile) GetCompletePath() string {
-	return path.Join(t.BaseFolder, t.TileName)
-}
-
-func (i *Image) Load() error {
-
-	data, err := ioutil.ReadFile(i.Filename)
-	if err != nil {
-		return errors.Wrap(err, "unable to read raw image")
+		err = t.loadMultiBandImage()
+	} else {
+		err = t.loadSingleBandImages()
 	}
-
-	im, err := tiff.Decode(bytes.NewBuffer(data))
 	if err != nil {
-		return errors.Wrap(err, "unable to decode tiff image")
-	}
-	imGray := im.(*image.Gray16)
-	pixelsRaw := imGray.Pix
-
-	i.SizeX = imGray.Rect.Max.X - imGray.Rect.Min.X
-	i.SizeY = imGray.Rect.Max.Y - imGray.Rect.Min.Y
-
-	pixels := make([]uint16, i.SizeX*i.SizeY)
-	for x := 0; x < len(pixels); x++ {
-		pixels[x] = uint16(pixelsRaw[x*2])<<8 | uint16(pixelsRaw[x*2+1])
+		return err
 	}
-	i.Pixels = pixels
 
-	return nil
+	return t.loadPairedImages()
 }
 
-func extractBand(filename string) string {
-	bandRaw := bandRegex.Find([]byte(filename))
-	if len(bandRaw) > 0 {
-		band := string(bandRaw)
-		return strings.ToLower(band[2 : len(band)-1])
-	}
-
-	return ""
+func (t *Tile) GetCompletePath() string {
+	return path.Join(t.BaseFolder, t.TileName)
 }
 
 func (t *Tile) loadMultiBandImage() error {
-	filename := path.Join(t.BaseFolder, t.TileName)
+	filename := t.GetCompletePath()
 
 	dataset, err := gdal.Open(filename, gdal.ReadOnly)
 	if err != nil {
@@ -176,7 +138,26 @@ func (t *Tile) loadMultiBandImage() error {
 	}
 	defer dataset.Close()
 
-	t.SplitMultiBand("C:\\data\\remote-sensing\\test1", "", map[int]string{})
+	// read every raster band that is not dropped by the band mapping
+	t.Images = make([]*Image, 0)
+	for band := 1; band <= dataset.RasterCount(); band++ {
+		mappedBand, ok := mapBand(t.BandMapping, band)
+		if !ok {
+			continue
+		}
+
+		img := &Image{
+			Band:      mappedBand,
+			Filename:  filename,
+			BandIndex: band,
+		}
+		err = img.loadBand(dataset)
+		if err != nil {
+			return errors.Wrapf(err, "unable to load band %d from '%s'", band, filename)
+		}
+
+		t.Images = append(t.Images, img)
+	}
 
 	return nil
 }
@@ -194,31 +175,33 @@ func (t *Tile) SplitMultiBand(outputFolder string, label string, bandMapping map
 
 	tileName := t.TileName
 	tileName = strings.TrimSuffix(tileName, path.Ext(tileName))
-	var folderName string
-	if label == "" {
-		folderName = path.Join(outputFolder, tileName)
-	} else {
-		folderName = path.Join(outputFolder, label)
-	}
+	folderName := t.SplitFolder(outputFolder, label)
 
 	os.MkdirAll(folderName, os.ModePerm)
 
 	for band := 1; band <= dataset.RasterCount(); band++ {
-		mappedBand, ok := bandMapping[band]
-		if ok && mappedBand == "" {
+		mappedBand, ok := mapBand(bandMapping, band)
+		if !ok {
 			continue
-		} else if !ok {
-			mappedBand = fmt.Sprintf("%02d", band)
 		}
 
-		name := fmt.Sprintf("%s_B%s.tiff", tileName, mappedBand)
-		dst := gdal.GDALTranslate(path.Join(folderName, name), dataset, []string{"-b", fmt.Sprintf("%d", band)})
+		dst := gdal.GDALTranslate(path.Join(folderName, splitBandFilename(tileName, mappedBand)), dataset, []string{"-b", fmt.Sprintf("%d", band)})
 		dst.Close()
 	}
 
 	return nil
 }
 
+// SplitFolder returns the folder the split bands of the tile are written to,
+// which is the label folder if a label is specified.
+func (t *Tile) SplitFolder(outputFolder string, label string) string {
+	if label == "" {
+		return path.Join(outputFolder, strings.TrimSuffix(t.TileName, path.Ext(t.TileName)))
+	}
+
+	return path.Join(outputFolder, label)
+}
+
 func (t *Tile) loadSingleBandImages() error {
 	// read the folder from the tile name
 	tileFolder := t.GetCompletePath()
@@ -247,3 +230,26 @@ func (t *Tile) loadSingleBandImages() error {
 
 	return nil
 }
+
+// mapBand returns the name of a raster band. Bands missing from the mapping
+// are named by their index and bands mapped to "" are dropped.
+// splitBandFilename names the file of a split band so the band can be read
+// back from the filename. Registered bands and indices use their canonical
+// name while unregistered band numbers are prefixed with B.
+func splitBandFilename(tileName string, band string) string {
+	name, err := canonicalBandName(band)
+	if err != nil {
+		name = "B" + band
+	}
+
+	return fmt.Sprintf("%s_%s.tiff", tileName, name)
+}
+
+func mapBand(bandMapping map[int]string, band int) (string, bool) {
+	mappedBand, ok := bandMapping[band]
+	if !ok {
+		return fmt.Sprintf("%02d", band), true
+	}
+
+	return mappedBand, mappedBand != ""
+}
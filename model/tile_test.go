@@ -0,0 +1,39 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestSplitBandFilenameRoundTrip(t *testing.T) {
+	bandMapping, err := ParseBandMapping("", "1:blue,2:nir2,3:ndvi,4:vv,5:B12")
+	if err != nil {
+		t.Fatalf("ParseBandMapping failed: %v", err)
+	}
+
+	tests := []struct {
+		band int
+		want string
+	}{
+		{1, "S2A_MSIL2A_20170613T101031_0_45_B02.tiff"},
+		{2, "S2A_MSIL2A_20170613T101031_0_45_B8A.tiff"},
+		{3, "S2A_MSIL2A_20170613T101031_0_45_NDVI.tiff"},
+		{4, "S2A_MSIL2A_20170613T101031_0_45_VV.tiff"},
+		{5, "S2A_MSIL2A_20170613T101031_0_45_B12.tiff"},
+		{6, "S2A_MSIL2A_20170613T101031_0_45_B06.tiff"},
+		{14, "S2A_MSIL2A_20170613T101031_0_45_B14.tiff"},
+	}
+
+	for _, test := range tests {
+		band, ok := mapBand(bandMapping, test.band)
+		if !ok {
+			t.Fatalf("band %d dropped", test.band)
+		}
+		filename := splitBandFilename("S2A_MSIL2A_20170613T101031_0_45", band)
+		if filename != test.want {
+			t.Errorf("band %d (%s) written to %s, want %s", test.band, band, filename, test.want)
+		}
+		if got := extractBand(filename); normalizeBandName(got) != normalizeBandName(band) {
+			t.Errorf("band %s read back from %s as %s", band, filename, got)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package process
+
+import (
+	"io"
+	"runtime"
+	"sync"
+)
+
+// ProcessFunc does the work for one item, running concurrently with other
+// items. The result is passed on to the aggregation.
+type ProcessFunc func(item *Item) (interface{}, error)
+
+// AggregateFunc combines the result of an item with the results of previous
+// items. It is never called concurrently.
+type AggregateFunc func(item *Item, result interface{}) error
+
+// Engine processes the items of a source using a pool of workers. At most
+// MaxInFlight items are being processed or waiting for aggregation at any
+// time, which bounds the memory used. Ordered engines aggregate results in
+// source order while unordered engines aggregate results as they complete.
+type Engine struct {
+	Workers     int
+	MaxInFlight int
+	Ordered     bool
+}
+
+type result struct {
+	item  *Item
+	value interface{}
+	err   error
+}
+
+// NewEngine creates an engine, defaulting to a worker per CPU and allowing
+// 4 items in flight per worker. A maximum in flight below the worker count
+// reduces the workers to keep within the maximum.
+func NewEngine(workers int, maxInFlight int, ordered bool) *Engine {
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	if maxInFlight < 1 {
+		maxInFlight = workers * 4
+	} else if maxInFlight < workers {
+		workers = maxInFlight
+	}
+
+	return &Engine{
+		Workers:     workers,
+		MaxInFlight: maxInFlight,
+		Ordered:     ordered,
+	}
+}
+
+// Run processes every item of the source, stopping at the first error.
+func (e *Engine) Run(source Source, process ProcessFunc, aggregate AggregateFunc) error {
+	jobs := make(chan *Item)
+	results := make(chan *result, e.MaxInFlight)
+	tokens := make(chan struct{}, e.MaxInFlight)
+	done := make(chan struct{})
+
+	// feed the workers, waiting for a token before reading each item
+	var sourceErr error
+	go func() {
+		defer close(jobs)
+		for index := 0; ; index++ {
+			select {
+			case tokens <- struct{}{}:
+			case <-done:
+				return
+			}
+
+			item, err := source.Next()
+			if err != nil {
+				if err != io.EOF {
+					sourceErr = err
+				}
+				return
+			}
+			item.Index = index
+
+			select {
+			case jobs <- item:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < e.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range jobs {
+				value, err := process(item)
+				results <- &result{item: item, value: value, err: err}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// aggregate on the calling goroutine, releasing a token per result
+	var firstErr error
+	pending := make(map[int]*result)
+	next := 0
+	fail := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+			close(done)
+		}
+	}
+	for r := range results {
+		if firstErr != nil {
+			continue
+		}
+		if r.err != nil {
+			fail(r.err)
+			continue
+		}
+
+		if !e.Ordered {
+			err := aggregate(r.item, r.value)
+			if err != nil {
+				fail(err)
+			}
+			<-tokens
+			continue
+		}
+
+		pending[r.item.Index] = r
+		for {
+			p, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+
+			err := aggregate(p.item, p.value)
+			<-tokens
+			if err != nil {
+				fail(err)
+				break
+			}
+		}
+	}
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	return sourceErr
+}
@@ -0,0 +1,261 @@
+package process
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// testItems creates items named item-0, item-1, ...
+func testItems(count int) []*Item {
+	items := make([]*Item, count)
+	for i := range items {
+		items[i] = &Item{Folder: "test", Name: fmt.Sprintf("item-%d", i)}
+	}
+
+	return items
+}
+
+// failingSource returns its items and then fails.
+type failingSource struct {
+	items Source
+}
+
+func (s *failingSource) Next() (*Item, error) {
+	item, err := s.items.Next()
+	if err == io.EOF {
+		return nil, errors.New("source failed")
+	}
+
+	return item, err
+}
+
+func (s *failingSource) Close() error {
+	return nil
+}
+
+// runWithTimeout runs the engine, failing the test if it does not finish.
+func runWithTimeout(t *testing.T, engine *Engine, source Source, process ProcessFunc, aggregate AggregateFunc) error {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() {
+		done <- engine.Run(source, process, aggregate)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(10 * time.Second):
+		t.Fatalf("engine did not finish")
+		return nil
+	}
+}
+
+// jitter sleeps for a short time that varies by item so items complete out
+// of order.
+func jitter(item *Item) {
+	time.Sleep(time.Duration((item.Index*7)%5) * time.Millisecond)
+}
+
+func TestNewEngineDefaults(t *testing.T) {
+	engine := NewEngine(0, 0, false)
+	if engine.Workers < 1 {
+		t.Errorf("default workers is %d", engine.Workers)
+	}
+	if engine.MaxInFlight != engine.Workers*4 {
+		t.Errorf("default max in flight is %d for %d workers", engine.MaxInFlight, engine.Workers)
+	}
+
+	engine = NewEngine(4, 0, true)
+	if engine.Workers != 4 || engine.MaxInFlight != 16 || !engine.Ordered {
+		t.Errorf("default max in flight not 4 per worker: %+v", engine)
+	}
+
+	engine = NewEngine(4, 2, true)
+	if engine.Workers != 2 || engine.MaxInFlight != 2 {
+		t.Errorf("max in flight below the workers did not reduce the workers: %+v", engine)
+	}
+}
+
+func TestEngineOrdered(t *testing.T) {
+	items := testItems(200)
+	engine := NewEngine(8, 16, true)
+
+	aggregated := make([]string, 0)
+	err := runWithTimeout(t, engine, NewItemSource(items), func(item *Item) (interface{}, error) {
+		jitter(item)
+		return item.Name, nil
+	}, func(item *Item, result interface{}) error {
+		if result.(string) != item.Name {
+			t.Errorf("result %v aggregated with item %s", result, item.Name)
+		}
+		aggregated = append(aggregated, item.Name)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	if len(aggregated) != len(items) {
+		t.Fatalf("aggregated %d of %d items", len(aggregated), len(items))
+	}
+	for i, name := range aggregated {
+		if name != items[i].Name {
+			t.Fatalf("item %d aggregated as %s, want %s", i, name, items[i].Name)
+		}
+	}
+}
+
+func TestEngineUnordered(t *testing.T) {
+	items := testItems(200)
+	engine := NewEngine(8, 16, false)
+
+	seen := make(map[string]int)
+	indices := make(map[int]bool)
+	err := runWithTimeout(t, engine, NewItemSource(items), func(item *Item) (interface{}, error) {
+		jitter(item)
+		return nil, nil
+	}, func(item *Item, result interface{}) error {
+		seen[item.Name]++
+		indices[item.Index] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	for _, item := range items {
+		if seen[item.Name] != 1 {
+			t.Errorf("item %s aggregated %d times", item.Name, seen[item.Name])
+		}
+	}
+	for i := range items {
+		if !indices[i] {
+			t.Errorf("no item with index %d", i)
+		}
+	}
+}
+
+func TestEngineMaxInFlight(t *testing.T) {
+	for _, engine := range []*Engine{NewEngine(4, 6, false), NewEngine(8, 2, false)} {
+		testEngineMaxInFlight(t, engine)
+	}
+}
+
+func testEngineMaxInFlight(t *testing.T, engine *Engine) {
+	t.Helper()
+
+	var inFlight int32
+	var mutex sync.Mutex
+	maxSeen := int32(0)
+	err := runWithTimeout(t, engine, NewItemSource(testItems(100)), func(item *Item) (interface{}, error) {
+		current := atomic.AddInt32(&inFlight, 1)
+		mutex.Lock()
+		if current > maxSeen {
+			maxSeen = current
+		}
+		mutex.Unlock()
+		jitter(item)
+		return nil, nil
+	}, func(item *Item, result interface{}) error {
+		// slow aggregation lets processed items pile up
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	if maxSeen > int32(engine.MaxInFlight) {
+		t.Errorf("%d items in flight, above the maximum of %d", maxSeen, engine.MaxInFlight)
+	}
+}
+
+func TestEngineErrors(t *testing.T) {
+	processErr := errors.New("process failed")
+	aggregateErr := errors.New("aggregate failed")
+	tests := []struct {
+		name      string
+		source    func() Source
+		process   ProcessFunc
+		aggregate AggregateFunc
+		want      string
+	}{
+		{
+			name:   "process",
+			source: func() Source { return NewItemSource(testItems(500)) },
+			process: func(item *Item) (interface{}, error) {
+				if item.Index == 50 {
+					return nil, processErr
+				}
+				return nil, nil
+			},
+			want: processErr.Error(),
+		},
+		{
+			name:    "first aggregate",
+			source:  func() Source { return NewItemSource(testItems(500)) },
+			process: func(item *Item) (interface{}, error) { return nil, nil },
+			aggregate: func(item *Item, result interface{}) error {
+				return aggregateErr
+			},
+			want: aggregateErr.Error(),
+		},
+		{
+			name:    "later aggregate",
+			source:  func() Source { return NewItemSource(testItems(500)) },
+			process: func(item *Item) (interface{}, error) { return nil, nil },
+			aggregate: func(item *Item, result interface{}) error {
+				if item.Index == 100 {
+					return aggregateErr
+				}
+				return nil
+			},
+			want: aggregateErr.Error(),
+		},
+		{
+			name:    "source",
+			source:  func() Source { return &failingSource{items: NewItemSource(testItems(20))} },
+			process: func(item *Item) (interface{}, error) { return nil, nil },
+			want:    "source failed",
+		},
+	}
+
+	for _, test := range tests {
+		for _, ordered := range []bool{false, true} {
+			aggregate := test.aggregate
+			if aggregate == nil {
+				aggregate = func(item *Item, result interface{}) error { return nil }
+			}
+			err := runWithTimeout(t, NewEngine(4, 8, ordered), test.source(), test.process, aggregate)
+			if err == nil || err.Error() != test.want {
+				t.Errorf("%s error (ordered %v) is %v, want %s", test.name, ordered, err, test.want)
+			}
+		}
+	}
+}
+
+func TestEngineStopsAfterError(t *testing.T) {
+	var processed int32
+	engine := NewEngine(2, 4, true)
+	err := runWithTimeout(t, engine, NewItemSource(testItems(10000)), func(item *Item) (interface{}, error) {
+		atomic.AddInt32(&processed, 1)
+		return nil, nil
+	}, func(item *Item, result interface{}) error {
+		return errors.New("stop")
+	})
+	if err == nil {
+		t.Fatalf("run succeeded after the aggregation failed")
+	}
+
+	// only the items already in flight when the aggregation failed are processed
+	if processed > int32(engine.MaxInFlight)*2 {
+		t.Errorf("%d items processed after the first failed", processed)
+	}
+}
@@ -0,0 +1,305 @@
+package process
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Partition is a named share of a dataset, such as the training set.
+type Partition struct {
+	Name     string
+	Fraction float64
+}
+
+// Assignment is the partition an item is assigned to.
+type Assignment struct {
+	Item      *Item
+	Labels    []string
+	Partition string
+}
+
+// Partitioner assigns multi-label items to partitions using iterative
+// stratification, which keeps the share of every label in each partition
+// close to the partition fraction, even for rare labels. Items can be grouped
+// so that a whole group, such as the patches of a scene, is assigned to the
+// same partition.
+type Partitioner struct {
+	Partitions  []*Partition
+	sampler     *Sampler
+	candidates  []*partitionCandidate
+	groups      map[string]*partitionCandidate
+	itemCount   int
+	labelCounts map[string]int
+	counts      map[string][]int
+	groupCounts []int
+}
+
+// partitionCandidate is a group of items assigned together, with the number
+// of items having each label.
+type partitionCandidate struct {
+	items        []*Item
+	itemLabels   [][]string
+	labelWeights map[string]int
+	key          float64
+	partition    int
+}
+
+// ParsePartitions parses a CSV list of partitions in the format
+// (name):(fraction), normalizing the fractions to sum to 1.
+func ParsePartitions(spec string) ([]*Partition, error) {
+	partitions := make([]*Partition, 0)
+	total := 0.0
+	seen := make(map[string]bool)
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		parts := strings.Split(field, ":")
+		if len(parts) != 2 {
+			return nil, errors.Errorf("partition '%s' not in the format (name):(fraction)", field)
+		}
+		name := strings.TrimSpace(parts[0])
+		fraction, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to parse fraction of partition '%s'", name)
+		}
+		if name == "" || fraction <= 0 {
+			return nil, errors.Errorf("partition '%s' needs a name and a positive fraction", field)
+		}
+		if seen[name] {
+			return nil, errors.Errorf("partition '%s' specified more than once", name)
+		}
+		seen[name] = true
+
+		partitions = append(partitions, &Partition{
+			Name:     name,
+			Fraction: fraction,
+		})
+		total += fraction
+	}
+	if len(partitions) == 0 {
+		return nil, errors.Errorf("no partitions specified")
+	}
+
+	for _, p := range partitions {
+		p.Fraction = p.Fraction / total
+	}
+
+	return partitions, nil
+}
+
+// FoldPartitions creates k equal partitions for k-fold cross validation.
+func FoldPartitions(k int) ([]*Partition, error) {
+	if k < 2 {
+		return nil, errors.Errorf("k-fold partitioning requires at least 2 folds")
+	}
+
+	partitions := make([]*Partition, k)
+	for i := range partitions {
+		partitions[i] = &Partition{
+			Name:     fmt.Sprintf("fold-%d", i+1),
+			Fraction: 1 / float64(k),
+		}
+	}
+
+	return partitions, nil
+}
+
+// NewPartitioner creates a partitioner, using the sampler to order items.
+func NewPartitioner(partitions []*Partition, sampler *Sampler) *Partitioner {
+	return &Partitioner{
+		Partitions:  partitions,
+		sampler:     sampler,
+		candidates:  make([]*partitionCandidate, 0),
+		groups:      make(map[string]*partitionCandidate),
+		labelCounts: make(map[string]int),
+	}
+}
+
+// Add adds an item with its labels to the items to partition. Items of the
+// same group are assigned to the same partition, with no group meaning the
+// item is assigned on its own. It is not safe for concurrent use.
+func (p *Partitioner) Add(item *Item, labels []string, group string) {
+	c := p.groups[group]
+	if c == nil {
+		c = &partitionCandidate{
+			labelWeights: make(map[string]int),
+			partition:    -1,
+		}
+		name := group
+		if name == "" {
+			name = item.Name
+		}
+		if p.sampler.Mode == SampleModeHash {
+			c.key = p.sampler.Hash(name)
+		} else {
+			c.key = p.sampler.float64()
+		}
+		p.candidates = append(p.candidates, c)
+		if group != "" {
+			p.groups[group] = c
+		}
+	}
+
+	c.items = append(c.items, item)
+	c.itemLabels = append(c.itemLabels, labels)
+	for _, label := range labels {
+		c.labelWeights[label]++
+		p.labelCounts[label]++
+	}
+	p.itemCount++
+}
+
+// Assign assigns every item to a partition. The label with the fewest
+// remaining items is handled first, assigning each group with the label to
+// the partition needing the most of that label, then the most items overall.
+func (p *Partitioner) Assign() []*Assignment {
+	desired := make([]float64, len(p.Partitions))
+	desiredLabels := make(map[string][]float64)
+	remaining := make(map[string][]*partitionCandidate)
+	for j, partition := range p.Partitions {
+		desired[j] = partition.Fraction * float64(p.itemCount)
+	}
+	for label, count := range p.labelCounts {
+		desiredLabels[label] = make([]float64, len(p.Partitions))
+		for j, partition := range p.Partitions {
+			desiredLabels[label][j] = partition.Fraction * float64(count)
+		}
+	}
+
+	sorted := make([]*partitionCandidate, len(p.candidates))
+	copy(sorted, p.candidates)
+	sort.Slice(sorted, func(i int, j int) bool {
+		return sorted[i].key < sorted[j].key
+	})
+	unlabelled := make([]*partitionCandidate, 0)
+	for _, c := range sorted {
+		c.partition = -1
+		if len(c.labelWeights) == 0 {
+			unlabelled = append(unlabelled, c)
+		}
+		for label := range c.labelWeights {
+			remaining[label] = append(remaining[label], c)
+		}
+	}
+
+	assign := func(c *partitionCandidate, j int) {
+		c.partition = j
+		desired[j] -= float64(len(c.items))
+		for label, weight := range c.labelWeights {
+			desiredLabels[label][j] -= float64(weight)
+		}
+	}
+
+	for {
+		// find the label with the fewest unassigned items
+		label := ""
+		fewest := math.MaxInt64
+		for l, candidates := range remaining {
+			unassigned := 0
+			for _, c := range candidates {
+				if c.partition < 0 {
+					unassigned += c.labelWeights[l]
+				}
+			}
+			if unassigned == 0 {
+				delete(remaining, l)
+				continue
+			}
+			if unassigned < fewest || (unassigned == fewest && l < label) {
+				label = l
+				fewest = unassigned
+			}
+		}
+		if label == "" {
+			break
+		}
+
+		for _, c := range remaining[label] {
+			if c.partition >= 0 {
+				continue
+			}
+			assign(c, p.mostNeeded(desiredLabels[label], desired))
+		}
+		delete(remaining, label)
+	}
+
+	for _, c := range unlabelled {
+		assign(c, p.mostNeeded(desired, desired))
+	}
+
+	p.counts = make(map[string][]int)
+	p.groupCounts = make([]int, len(p.Partitions))
+	assignments := make([]*Assignment, 0, p.itemCount)
+	for _, c := range p.candidates {
+		p.groupCounts[c.partition]++
+		for i, item := range c.items {
+			for _, label := range c.itemLabels[i] {
+				if p.counts[label] == nil {
+					p.counts[label] = make([]int, len(p.Partitions))
+				}
+				p.counts[label][c.partition]++
+			}
+			assignments = append(assignments, &Assignment{
+				Item:      item,
+				Labels:    c.itemLabels[i],
+				Partition: p.Partitions[c.partition].Name,
+			})
+		}
+	}
+
+	return assignments
+}
+
+// GroupCounts returns the number of groups, or ungrouped items, assigned to
+// each partition, in partition order.
+func (p *Partitioner) GroupCounts() []int {
+	counts := make([]int, len(p.Partitions))
+	copy(counts, p.groupCounts)
+
+	return counts
+}
+
+// mostNeeded returns the partition with the highest need, breaking ties
+// using the overall need and then the partition order.
+func (p *Partitioner) mostNeeded(need []float64, overall []float64) int {
+	best := 0
+	for j := 1; j < len(need); j++ {
+		if need[j] > need[best] || (need[j] == need[best] && overall[j] > overall[best]) {
+			best = j
+		}
+	}
+
+	return best
+}
+
+// Labels returns the labels from the most to the least common.
+func (p *Partitioner) Labels() []string {
+	labels := make([]string, 0, len(p.labelCounts))
+	for label := range p.labelCounts {
+		labels = append(labels, label)
+	}
+	sort.Slice(labels, func(i int, j int) bool {
+		if p.labelCounts[labels[i]] == p.labelCounts[labels[j]] {
+			return labels[i] < labels[j]
+		}
+		return p.labelCounts[labels[i]] > p.labelCounts[labels[j]]
+	})
+
+	return labels
+}
+
+// LabelCounts returns the number of items with the label assigned to each
+// partition, in partition order.
+func (p *Partitioner) LabelCounts(label string) []int {
+	counts := make([]int, len(p.Partitions))
+	copy(counts, p.counts[label])
+
+	return counts
+}
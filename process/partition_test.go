@@ -0,0 +1,226 @@
+package process
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+// testLabels gives item i a deterministic set of labels, with label A on
+// most items and the rarer labels on fewer items.
+func testLabels(i int) []string {
+	labels := make([]string, 0)
+	if i%5 != 0 {
+		labels = append(labels, "A")
+	}
+	if i%3 == 0 {
+		labels = append(labels, "B")
+	}
+	if i%17 == 0 {
+		labels = append(labels, "C")
+	}
+	if i%41 == 0 {
+		labels = append(labels, "D")
+	}
+
+	return labels
+}
+
+// partitionItems partitions the items, with groups of the group size sharing
+// a group, returning the partition of each item by name.
+func partitionItems(t *testing.T, items []*Item, seed int64, groupSize int) (*Partitioner, map[string]string) {
+	t.Helper()
+	partitions, err := ParsePartitions("train:0.7,val:0.15,test:0.15")
+	if err != nil {
+		t.Fatalf("ParsePartitions failed: %v", err)
+	}
+	sampler, err := NewSampler(1, seed, SampleModeHash)
+	if err != nil {
+		t.Fatalf("NewSampler failed: %v", err)
+	}
+
+	partitioner := NewPartitioner(partitions, sampler)
+	for _, item := range items {
+		var i int
+		fmt.Sscanf(item.Name, "item-%d", &i)
+		group := ""
+		if groupSize > 0 {
+			group = fmt.Sprintf("group-%d", i/groupSize)
+		}
+		partitioner.Add(item, testLabels(i), group)
+	}
+
+	assigned := make(map[string]string)
+	for _, a := range partitioner.Assign() {
+		if _, ok := assigned[a.Item.Name]; ok {
+			t.Fatalf("item %s assigned more than once", a.Item.Name)
+		}
+		assigned[a.Item.Name] = a.Partition
+	}
+	if len(assigned) != len(items) {
+		t.Fatalf("assigned %d of %d items", len(assigned), len(items))
+	}
+
+	return partitioner, assigned
+}
+
+func TestParsePartitions(t *testing.T) {
+	partitions, err := ParsePartitions(" train:7, val:1.5 ,test:1.5")
+	if err != nil {
+		t.Fatalf("ParsePartitions failed: %v", err)
+	}
+
+	want := []*Partition{{"train", 0.7}, {"val", 0.15}, {"test", 0.15}}
+	if len(partitions) != len(want) {
+		t.Fatalf("parsed %d partitions, want %d", len(partitions), len(want))
+	}
+	for i, p := range partitions {
+		if p.Name != want[i].Name || math.Abs(p.Fraction-want[i].Fraction) > 1e-9 {
+			t.Errorf("partition %d is %s:%v, want %s:%v", i, p.Name, p.Fraction, want[i].Name, want[i].Fraction)
+		}
+	}
+}
+
+func TestParsePartitionsErrors(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"", "no partitions specified"},
+		{" , ", "no partitions specified"},
+		{"train:0.8,train:0.2", "partition 'train' specified more than once"},
+		{"train:0.8,val:0", "needs a name and a positive fraction"},
+		{"train:0.8,val:-0.2", "needs a name and a positive fraction"},
+		{":0.5,val:0.5", "needs a name and a positive fraction"},
+		{"train", "not in the format (name):(fraction)"},
+		{"train:0.5:0.5", "not in the format (name):(fraction)"},
+		{"train:most", "unable to parse fraction of partition 'train'"},
+	}
+
+	for _, test := range tests {
+		_, err := ParsePartitions(test.spec)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("ParsePartitions(%q) error is %v, want %q", test.spec, err, test.want)
+		}
+	}
+}
+
+func TestFoldPartitions(t *testing.T) {
+	partitions, err := FoldPartitions(4)
+	if err != nil {
+		t.Fatalf("FoldPartitions failed: %v", err)
+	}
+	if len(partitions) != 4 {
+		t.Fatalf("created %d folds, want 4", len(partitions))
+	}
+	for i, p := range partitions {
+		if p.Name != fmt.Sprintf("fold-%d", i+1) || p.Fraction != 0.25 {
+			t.Errorf("fold %d is %s:%v", i, p.Name, p.Fraction)
+		}
+	}
+
+	for _, k := range []int{-1, 0, 1} {
+		_, err := FoldPartitions(k)
+		if err == nil {
+			t.Errorf("FoldPartitions(%d) succeeded", k)
+		}
+	}
+}
+
+func TestPartitionerLabelShares(t *testing.T) {
+	items := testItems(4000)
+	partitioner, assigned := partitionItems(t, items, 42, 0)
+
+	totals := make(map[string]int)
+	for _, p := range assigned {
+		totals[p]++
+	}
+	for j, p := range partitioner.Partitions {
+		share := float64(totals[p.Name]) / float64(len(items))
+		if math.Abs(share-p.Fraction) > 0.01 {
+			t.Errorf("partition %s has %v of the items, want %v", p.Name, share, p.Fraction)
+		}
+		if partitioner.GroupCounts()[j] != totals[p.Name] {
+			t.Errorf("partition %s has %d groups for %d ungrouped items", p.Name, partitioner.GroupCounts()[j], totals[p.Name])
+		}
+	}
+
+	labels := partitioner.Labels()
+	if strings.Join(labels, ",") != "A,B,C,D" {
+		t.Errorf("labels are %v, want most to least common", labels)
+	}
+	for _, label := range labels {
+		counts := partitioner.LabelCounts(label)
+		total := 0
+		for _, c := range counts {
+			total += c
+		}
+		for j, p := range partitioner.Partitions {
+			// each partition is within an item of its share of the label
+			want := p.Fraction * float64(total)
+			if math.Abs(float64(counts[j])-want) > 1.5 {
+				t.Errorf("partition %s has %d of the %d items labelled %s, want about %.1f", p.Name, counts[j], total, label, want)
+			}
+		}
+	}
+}
+
+func TestPartitionerGroups(t *testing.T) {
+	items := testItems(3000)
+	partitioner, assigned := partitionItems(t, items, 42, 10)
+
+	groups := make(map[string]string)
+	for name, p := range assigned {
+		var i int
+		fmt.Sscanf(name, "item-%d", &i)
+		group := fmt.Sprintf("group-%d", i/10)
+		if other, ok := groups[group]; ok && other != p {
+			t.Fatalf("group %s split across partitions %s and %s", group, other, p)
+		}
+		groups[group] = p
+	}
+
+	groupTotal := 0
+	for _, c := range partitioner.GroupCounts() {
+		groupTotal += c
+	}
+	if groupTotal != 300 {
+		t.Errorf("counted %d groups, want 300", groupTotal)
+	}
+
+	totals := make(map[string]int)
+	for _, p := range assigned {
+		totals[p]++
+	}
+	for _, p := range partitioner.Partitions {
+		share := float64(totals[p.Name]) / float64(len(items))
+		if math.Abs(share-p.Fraction) > 0.02 {
+			t.Errorf("grouped partition %s has %v of the items, want %v", p.Name, share, p.Fraction)
+		}
+	}
+}
+
+func TestPartitionerReproducible(t *testing.T) {
+	items := testItems(2000)
+	_, first := partitionItems(t, items, 42, 0)
+	_, again := partitionItems(t, testItems(2000), 42, 0)
+	_, shuffledOrder := partitionItems(t, shuffled(testItems(2000), 9), 42, 0)
+	_, other := partitionItems(t, testItems(2000), 43, 0)
+
+	differences := 0
+	for name, p := range first {
+		if again[name] != p {
+			t.Fatalf("item %s assigned to %s then %s with the same seed", name, p, again[name])
+		}
+		if shuffledOrder[name] != p {
+			t.Fatalf("item %s assigned to %s then %s when added in another order", name, p, shuffledOrder[name])
+		}
+		if other[name] != p {
+			differences++
+		}
+	}
+	if differences == 0 {
+		t.Errorf("seeds 42 and 43 assigned every item to the same partition")
+	}
+}
@@ -0,0 +1,95 @@
+package process
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// Selection is an item kept by a reservoir along with the value it was
+// offered with.
+type Selection struct {
+	Item  *Item
+	Value interface{}
+	key   float64
+}
+
+// Reservoir keeps a uniform sample of exactly Size items from a stream
+// without holding on to the other items. Hash sampling keeps the items with
+// the lowest hash of their name, which does not depend on the order items are
+// offered in, while random sampling uses reservoir sampling.
+type Reservoir struct {
+	Size     int
+	Offered  int
+	sampler  *Sampler
+	selected selectionHeap
+}
+
+// selectionHeap is a max heap on the hash so the highest is replaced first.
+type selectionHeap []*Selection
+
+// NewReservoir creates a reservoir selecting items using the sampler mode.
+func NewReservoir(size int, sampler *Sampler) *Reservoir {
+	return &Reservoir{
+		Size:     size,
+		sampler:  sampler,
+		selected: make(selectionHeap, 0, size),
+	}
+}
+
+// Offer adds the item to the sample if it is selected, possibly evicting a
+// previously selected item. It is not safe for concurrent use.
+func (r *Reservoir) Offer(item *Item, value interface{}) {
+	r.Offered++
+	if r.Size < 1 {
+		return
+	}
+
+	selection := &Selection{
+		Item:  item,
+		Value: value,
+	}
+	if r.sampler.Mode == SampleModeHash {
+		selection.key = r.sampler.Hash(item.Name)
+		if len(r.selected) < r.Size {
+			heap.Push(&r.selected, selection)
+		} else if selection.key < r.selected[0].key {
+			r.selected[0] = selection
+			heap.Fix(&r.selected, 0)
+		}
+		return
+	}
+
+	if len(r.selected) < r.Size {
+		r.selected = append(r.selected, selection)
+	} else if j := r.sampler.intn(r.Offered); j < r.Size {
+		r.selected[j] = selection
+	}
+}
+
+// Selected returns the selected items in the order they were offered.
+func (r *Reservoir) Selected() []*Selection {
+	selected := make([]*Selection, len(r.selected))
+	copy(selected, r.selected)
+	sort.Slice(selected, func(i int, j int) bool {
+		return selected[i].Item.Index < selected[j].Item.Index
+	})
+
+	return selected
+}
+
+func (h selectionHeap) Len() int           { return len(h) }
+func (h selectionHeap) Less(i, j int) bool { return h[i].key > h[j].key }
+func (h selectionHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *selectionHeap) Push(x interface{}) {
+	*h = append(*h, x.(*Selection))
+}
+
+func (h *selectionHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+
+	return x
+}
@@ -0,0 +1,127 @@
+package process
+
+import (
+	"testing"
+)
+
+// offerAll offers the items in order, setting their index as a source would.
+func offerAll(reservoir *Reservoir, items []*Item) {
+	for i, item := range items {
+		item.Index = i
+		reservoir.Offer(item, item.Name)
+	}
+}
+
+func selectedNames(reservoir *Reservoir) map[string]bool {
+	names := make(map[string]bool)
+	for _, s := range reservoir.Selected() {
+		names[s.Item.Name] = true
+	}
+
+	return names
+}
+
+func TestReservoirSize(t *testing.T) {
+	tests := []struct {
+		size  int
+		items int
+		want  int
+	}{
+		{10, 1000, 10},
+		{10, 5, 5},
+		{10, 10, 10},
+		{0, 100, 0},
+	}
+
+	for _, mode := range []string{SampleModeHash, SampleModeRandom} {
+		for _, test := range tests {
+			sampler, err := NewSampler(1, 42, mode)
+			if err != nil {
+				t.Fatalf("NewSampler failed: %v", err)
+			}
+			reservoir := NewReservoir(test.size, sampler)
+			offerAll(reservoir, testItems(test.items))
+
+			selected := reservoir.Selected()
+			if len(selected) != test.want {
+				t.Errorf("%s reservoir of %d selected %d of %d items, want %d", mode, test.size, len(selected), test.items, test.want)
+			}
+			if reservoir.Offered != test.items {
+				t.Errorf("%s reservoir offered %d items, want %d", mode, reservoir.Offered, test.items)
+			}
+			if len(selectedNames(reservoir)) != len(selected) {
+				t.Errorf("%s reservoir selected an item more than once", mode)
+			}
+			for i, s := range selected {
+				if s.Value.(string) != s.Item.Name {
+					t.Errorf("%s reservoir lost the value of %s", mode, s.Item.Name)
+				}
+				if i > 0 && selected[i-1].Item.Index >= s.Item.Index {
+					t.Errorf("%s reservoir selection not in offered order", mode)
+				}
+			}
+		}
+	}
+}
+
+func TestReservoirHashIndependentOfOrder(t *testing.T) {
+	items := testItems(5000)
+	sampler, _ := NewSampler(1, 42, SampleModeHash)
+	reservoir := NewReservoir(100, sampler)
+	offerAll(reservoir, items)
+	want := selectedNames(reservoir)
+
+	for shuffle := int64(1); shuffle <= 3; shuffle++ {
+		other := NewReservoir(100, sampler)
+		offerAll(other, shuffled(testItems(5000), shuffle))
+		got := selectedNames(other)
+		if len(got) != len(want) {
+			t.Fatalf("shuffle %d selected %d items, want %d", shuffle, len(got), len(want))
+		}
+		for name := range want {
+			if !got[name] {
+				t.Fatalf("shuffle %d did not select %s", shuffle, name)
+			}
+		}
+	}
+}
+
+func TestReservoirHashKeepsLowest(t *testing.T) {
+	items := testItems(1000)
+	sampler, _ := NewSampler(1, 7, SampleModeHash)
+	reservoir := NewReservoir(50, sampler)
+	offerAll(reservoir, items)
+
+	// every selected item hashes below every rejected item
+	highest := 0.0
+	selected := selectedNames(reservoir)
+	for name := range selected {
+		if h := sampler.Hash(name); h > highest {
+			highest = h
+		}
+	}
+	for _, item := range items {
+		if !selected[item.Name] && sampler.Hash(item.Name) < highest {
+			t.Fatalf("rejected %s hashes below the selected items", item.Name)
+		}
+	}
+}
+
+func TestReservoirRandomUniform(t *testing.T) {
+	counts := make([]int, 20)
+	sampler, _ := NewSampler(1, 3, SampleModeRandom)
+	for run := 0; run < 2000; run++ {
+		reservoir := NewReservoir(5, sampler)
+		offerAll(reservoir, testItems(len(counts)))
+		for _, s := range reservoir.Selected() {
+			counts[s.Item.Index]++
+		}
+	}
+
+	// each item is selected in about a quarter of the runs
+	for i, c := range counts {
+		if c < 400 || c > 600 {
+			t.Errorf("item %d selected %d times in 2000 runs", i, c)
+		}
+	}
+}
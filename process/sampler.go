@@ -0,0 +1,112 @@
+package process
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math/rand"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// SampleModeRandom selects items using a random number generator.
+	SampleModeRandom = "random"
+	// SampleModeHash selects items using a hash of the seed and tile name.
+	SampleModeHash = "hash"
+)
+
+// Sampler selects items at a sample rate. Random sampling depends on the
+// order items are sampled in, so is only reproducible with a single worker.
+// Hash sampling always selects the same items for a seed and rate.
+type Sampler struct {
+	Rate   float64
+	Seed   int64
+	Mode   string
+	random *rand.Rand
+	mutex  sync.Mutex
+}
+
+// NewSampler creates a sampler, with a seed of 0 meaning a random sample is
+// not reproducible. Without a mode, seeded samplers hash so that a seed always
+// selects the same items, and other samplers are random.
+func NewSampler(rate float64, seed int64, mode string) (*Sampler, error) {
+	mode = strings.ToLower(mode)
+	if mode == "" {
+		mode = SampleModeRandom
+		if seed != 0 {
+			mode = SampleModeHash
+		}
+	}
+	if mode != SampleModeRandom && mode != SampleModeHash {
+		return nil, errors.Errorf("unknown sample mode '%s' (expected %s or %s)", mode, SampleModeRandom, SampleModeHash)
+	}
+
+	randomSeed := seed
+	if randomSeed == 0 {
+		randomSeed = time.Now().UnixNano()
+	}
+
+	return &Sampler{
+		Rate:   rate,
+		Seed:   seed,
+		Mode:   mode,
+		random: rand.New(rand.NewSource(randomSeed)),
+	}, nil
+}
+
+// Select returns true if the item is part of the sample.
+func (s *Sampler) Select(item *Item) bool {
+	if s.Mode == SampleModeHash {
+		return s.Hash(item.Name) < s.Rate
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.random.Float64() < s.Rate
+}
+
+// Hash maps the tile name and seed to a value in [0, 1). The extension is
+// ignored so a tile and its multiband image hash to the same value.
+func (s *Sampler) Hash(name string) float64 {
+	name = strings.TrimSuffix(name, path.Ext(name))
+
+	seed := make([]byte, 8)
+	binary.LittleEndian.PutUint64(seed, uint64(s.Seed))
+	h := fnv.New64a()
+	h.Write(seed)
+	h.Write([]byte(name))
+
+	// use the top 53 bits to get an evenly distributed float64
+	return float64(mix(h.Sum64())>>11) / float64(uint64(1)<<53)
+}
+
+// mix is the murmur3 finalizer, spreading the last bytes hashed by FNV over
+// all the bits since names often only differ in their last characters.
+func mix(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+
+	return h
+}
+
+func (s *Sampler) intn(n int) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.random.Intn(n)
+}
+
+func (s *Sampler) float64() float64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.random.Float64()
+}
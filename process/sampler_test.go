@@ -0,0 +1,133 @@
+package process
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// selectNames returns the names of the items selected by the sampler.
+func selectNames(sampler *Sampler, items []*Item) map[string]bool {
+	selected := make(map[string]bool)
+	for _, item := range items {
+		if sampler.Select(item) {
+			selected[item.Name] = true
+		}
+	}
+
+	return selected
+}
+
+func shuffled(items []*Item, seed int64) []*Item {
+	shuffled := make([]*Item, len(items))
+	copy(shuffled, items)
+	random := rand.New(rand.NewSource(seed))
+	random.Shuffle(len(shuffled), func(i int, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+
+	return shuffled
+}
+
+func TestNewSamplerMode(t *testing.T) {
+	tests := []struct {
+		seed int64
+		mode string
+		want string
+	}{
+		{0, "", SampleModeRandom},
+		{42, "", SampleModeHash},
+		{0, "hash", SampleModeHash},
+		{42, "Random", SampleModeRandom},
+	}
+
+	for _, test := range tests {
+		sampler, err := NewSampler(0.5, test.seed, test.mode)
+		if err != nil {
+			t.Errorf("NewSampler(%d, %q) failed: %v", test.seed, test.mode, err)
+			continue
+		}
+		if sampler.Mode != test.want {
+			t.Errorf("NewSampler(%d, %q) mode is %s, want %s", test.seed, test.mode, sampler.Mode, test.want)
+		}
+	}
+
+	_, err := NewSampler(0.5, 42, "stratified")
+	if err == nil {
+		t.Errorf("unknown sample mode accepted")
+	}
+}
+
+func TestSamplerSelectIndependentOfOrder(t *testing.T) {
+	items := testItems(5000)
+	sampler, err := NewSampler(0.2, 42, "")
+	if err != nil {
+		t.Fatalf("NewSampler failed: %v", err)
+	}
+	want := selectNames(sampler, items)
+
+	for shuffle := int64(1); shuffle <= 3; shuffle++ {
+		other, err := NewSampler(0.2, 42, "")
+		if err != nil {
+			t.Fatalf("NewSampler failed: %v", err)
+		}
+		got := selectNames(other, shuffled(items, shuffle))
+		if len(got) != len(want) {
+			t.Fatalf("shuffle %d selected %d items, want %d", shuffle, len(got), len(want))
+		}
+		for name := range want {
+			if !got[name] {
+				t.Fatalf("shuffle %d did not select %s", shuffle, name)
+			}
+		}
+	}
+}
+
+func TestSamplerSelectRate(t *testing.T) {
+	items := testItems(20000)
+	for _, rate := range []float64{0.01, 0.1, 0.5, 0.9} {
+		sampler, err := NewSampler(rate, 7, SampleModeHash)
+		if err != nil {
+			t.Fatalf("NewSampler failed: %v", err)
+		}
+		fraction := float64(len(selectNames(sampler, items))) / float64(len(items))
+		if math.Abs(fraction-rate) > 0.02 {
+			t.Errorf("rate %v selected a fraction of %v", rate, fraction)
+		}
+	}
+}
+
+func TestSamplerSeeds(t *testing.T) {
+	items := testItems(2000)
+	first, _ := NewSampler(0.5, 1, SampleModeHash)
+	second, _ := NewSampler(0.5, 2, SampleModeHash)
+	a := selectNames(first, items)
+	b := selectNames(second, items)
+
+	shared := 0
+	for name := range a {
+		if b[name] {
+			shared++
+		}
+	}
+	// independent samples share about a quarter of the items
+	if shared > len(items)*3/8 {
+		t.Errorf("seeds 1 and 2 share %d of %d and %d selected items", shared, len(a), len(b))
+	}
+}
+
+func TestSamplerHash(t *testing.T) {
+	sampler, _ := NewSampler(0.5, 42, SampleModeHash)
+	name := "S2A_MSIL2A_20170613T101031_45_62"
+	if sampler.Hash(name) != sampler.Hash(name+".tif") {
+		t.Errorf("extension changes the hash")
+	}
+
+	for i := 0; i < 1000; i++ {
+		value := sampler.Hash(fmt.Sprintf("%s_%d", name, i))
+		if value < 0 || value >= 1 {
+			t.Fatalf("hash %v outside [0, 1)", value)
+		}
+	}
+}
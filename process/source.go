@@ -0,0 +1,186 @@
+package process
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultBatchSize is the number of directory entries read at once.
+const DefaultBatchSize = 1000
+
+// Item is an entry of a source folder to process, such as the folder of a
+// tile or a multiband image.
+type Item struct {
+	Index  int
+	Folder string
+	Name   string
+}
+
+// Source provides the items to process.
+type Source interface {
+	// Next returns the next item, or io.EOF once all items have been returned.
+	Next() (*Item, error)
+	// Close releases the files used by the source.
+	Close() error
+}
+
+// folderSource streams the entries of a folder in batches, without sorting or
+// stating them, so items are available as soon as the first batch is read.
+type folderSource struct {
+	folder    string
+	dir       *os.File
+	batchSize int
+	batch     []string
+	current   int
+}
+
+// listSource reads the items from a file listing one entry per line, either
+// relative to the source folder or as a complete path.
+type listSource struct {
+	folder   string
+	filename string
+	file     *os.File
+	scanner  *bufio.Scanner
+}
+
+// itemSource provides a fixed list of items.
+type itemSource struct {
+	items   []*Item
+	current int
+}
+
+// NewSource creates a source reading the items from the file list if one is
+// specified, and from the entries of the folder otherwise.
+func NewSource(folder string, fileList string, batchSize int) (Source, error) {
+	if fileList != "" {
+		return NewListSource(folder, fileList)
+	}
+
+	return NewFolderSource(folder, batchSize)
+}
+
+// NewFolderSource creates a source streaming the entries of a folder.
+func NewFolderSource(folder string, batchSize int) (Source, error) {
+	dir, err := os.Open(folder)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to open folder '%s'", folder)
+	}
+	if batchSize < 1 {
+		batchSize = DefaultBatchSize
+	}
+
+	return &folderSource{
+		folder:    folder,
+		dir:       dir,
+		batchSize: batchSize,
+	}, nil
+}
+
+// NewListSource creates a source streaming the entries of a file list.
+func NewListSource(folder string, filename string) (Source, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to open file list '%s'", filename)
+	}
+
+	return &listSource{
+		folder:   folder,
+		filename: filename,
+		file:     file,
+		scanner:  bufio.NewScanner(file),
+	}, nil
+}
+
+func (s *folderSource) Next() (*Item, error) {
+	if s.current >= len(s.batch) {
+		names, err := s.dir.Readdirnames(s.batchSize)
+		if err == io.EOF || (err == nil && len(names) == 0) {
+			return nil, io.EOF
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "unable to read contents of '%s'", s.folder)
+		}
+		s.batch = names
+		s.current = 0
+	}
+
+	item := &Item{
+		Folder: s.folder,
+		Name:   s.batch[s.current],
+	}
+	s.current++
+
+	return item, nil
+}
+
+func (s *folderSource) Close() error {
+	return s.dir.Close()
+}
+
+func (s *listSource) Next() (*Item, error) {
+	for s.scanner.Scan() {
+		entry := strings.TrimSpace(s.scanner.Text())
+		if entry == "" {
+			continue
+		}
+
+		// complete paths may point outside the source folder
+		folder, name := path.Split(strings.TrimSuffix(entry, "/"))
+		if !path.IsAbs(entry) {
+			folder = path.Join(s.folder, folder)
+		}
+
+		return &Item{
+			Folder: path.Clean(folder),
+			Name:   name,
+		}, nil
+	}
+
+	err := s.scanner.Err()
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read file list '%s'", s.filename)
+	}
+
+	return nil, io.EOF
+}
+
+func (s *listSource) Close() error {
+	return s.file.Close()
+}
+
+// NewItemSource creates a source providing copies of the items.
+func NewItemSource(items []*Item) Source {
+	return &itemSource{
+		items: items,
+	}
+}
+
+func (s *itemSource) Next() (*Item, error) {
+	if s.current >= len(s.items) {
+		return nil, io.EOF
+	}
+	item := *s.items[s.current]
+	s.current++
+
+	return &item, nil
+}
+
+func (s *itemSource) Close() error {
+	return nil
+}
+
+// Path returns the complete path of the item.
+func (i *Item) Path() string {
+	return path.Join(i.Folder, i.Name)
+}
+
+// IsDir returns true if the item is a folder. Sources do not stat entries so
+// the check is left to the workers.
+func (i *Item) IsDir() bool {
+	info, err := os.Stat(i.Path())
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,247 @@
+package process
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// StratifyQuota selects up to a fixed number of items for every label.
+	StratifyQuota = "quota"
+	// StratifyProportional selects a number of items with every label
+	// represented in proportion to its frequency, keeping at least one item
+	// per label.
+	StratifyProportional = "proportional"
+	// StratifyInverse selects a number of items weighting each item by the
+	// inverse frequency of its rarest label to balance the labels.
+	StratifyInverse = "inverse"
+)
+
+// Stratum is the label distribution of a stratified sample.
+type Stratum struct {
+	Label     string
+	Available int
+	Target    int
+	Achieved  int
+}
+
+// Stratifier selects a sample of multi-label items stratified by label. An
+// item counts towards every one of its labels so labels that occur with
+// common labels may exceed their target.
+type Stratifier struct {
+	Mode        string
+	Quota       int
+	Count       int
+	sampler     *Sampler
+	candidates  []*candidate
+	labelCounts map[string]int
+	targets     map[string]int
+	achieved    map[string]int
+}
+
+type candidate struct {
+	item     *Item
+	labels   []string
+	key      float64
+	selected bool
+}
+
+// NewStratifier creates a stratifier. Quota sampling uses the quota per label
+// while the other modes select count items in total.
+func NewStratifier(mode string, quota int, count int, sampler *Sampler) (*Stratifier, error) {
+	mode = strings.ToLower(mode)
+	switch mode {
+	case StratifyQuota:
+		if quota < 1 {
+			return nil, errors.Errorf("stratify mode '%s' requires a quota", mode)
+		}
+	case StratifyProportional, StratifyInverse:
+		if count < 1 {
+			return nil, errors.Errorf("stratify mode '%s' requires a count", mode)
+		}
+	default:
+		return nil, errors.Errorf("unknown stratify mode '%s' (expected %s, %s or %s)", mode, StratifyQuota, StratifyProportional, StratifyInverse)
+	}
+
+	return &Stratifier{
+		Mode:        mode,
+		Quota:       quota,
+		Count:       count,
+		sampler:     sampler,
+		candidates:  make([]*candidate, 0),
+		labelCounts: make(map[string]int),
+	}, nil
+}
+
+// Add adds an item with its labels to the items to select from. It is not
+// safe for concurrent use.
+func (s *Stratifier) Add(item *Item, labels []string) {
+	c := &candidate{
+		item:   item,
+		labels: labels,
+	}
+	if s.sampler.Mode == SampleModeHash {
+		c.key = s.sampler.Hash(item.Name)
+	} else {
+		c.key = s.sampler.float64()
+	}
+	s.candidates = append(s.candidates, c)
+
+	for _, label := range labels {
+		s.labelCounts[label]++
+	}
+}
+
+// Select selects the items, returning them in the order they were added.
+func (s *Stratifier) Select() []*Item {
+	s.targets = make(map[string]int)
+	s.achieved = make(map[string]int)
+	for _, c := range s.candidates {
+		c.selected = false
+	}
+
+	if s.Mode == StratifyInverse {
+		s.selectInverse()
+	} else {
+		s.selectGreedy()
+	}
+
+	items := make([]*Item, 0)
+	for _, c := range s.candidates {
+		if c.selected {
+			items = append(items, c.item)
+		}
+	}
+
+	return items
+}
+
+// selectGreedy fills the targets starting with the rarest label, taking the
+// items of each label in key order until the label reaches its target.
+func (s *Stratifier) selectGreedy() {
+	labels := s.labels()
+	for _, label := range labels {
+		available := s.labelCounts[label]
+		target := s.Quota
+		if s.Mode == StratifyProportional {
+			target = int(math.Round(float64(s.Count*available) / float64(len(s.candidates))))
+			if target < 1 {
+				target = 1
+			}
+		}
+		if target > available {
+			target = available
+		}
+		s.targets[label] = target
+	}
+
+	byLabel := make(map[string][]*candidate)
+	for _, c := range s.candidates {
+		for _, label := range c.labels {
+			byLabel[label] = append(byLabel[label], c)
+		}
+	}
+
+	total := 0
+	for _, label := range labels {
+		candidates := byLabel[label]
+		sort.Slice(candidates, func(i int, j int) bool {
+			return candidates[i].key < candidates[j].key
+		})
+		for _, c := range candidates {
+			if s.achieved[label] >= s.targets[label] || (s.Mode == StratifyProportional && total >= s.Count) {
+				break
+			}
+			if !c.selected {
+				s.selectCandidate(c)
+				total++
+			}
+		}
+	}
+}
+
+// selectInverse uses weighted sampling without replacement, keeping the items
+// with the highest key^(1/weight).
+func (s *Stratifier) selectInverse() {
+	// an even split of the label occurrences is the balanced target
+	labels := s.labels()
+	occurrences := 0
+	for _, c := range s.candidates {
+		occurrences += len(c.labels)
+	}
+	for _, label := range labels {
+		target := 0
+		if len(s.candidates) > 0 {
+			target = int(math.Round(float64(s.Count*occurrences) / float64(len(s.candidates)*len(labels))))
+		}
+		if target > s.labelCounts[label] {
+			target = s.labelCounts[label]
+		}
+		s.targets[label] = target
+	}
+
+	weighted := make([]*candidate, 0, len(s.candidates))
+	scores := make(map[*candidate]float64)
+	for _, c := range s.candidates {
+		weight := 0.0
+		for _, label := range c.labels {
+			weight = math.Max(weight, 1/float64(s.labelCounts[label]))
+		}
+		if weight == 0 {
+			continue
+		}
+		key := math.Max(c.key, math.SmallestNonzeroFloat64)
+		scores[c] = math.Log(key) / weight
+		weighted = append(weighted, c)
+	}
+	sort.Slice(weighted, func(i int, j int) bool {
+		return scores[weighted[i]] > scores[weighted[j]]
+	})
+
+	for i := 0; i < len(weighted) && i < s.Count; i++ {
+		s.selectCandidate(weighted[i])
+	}
+}
+
+func (s *Stratifier) selectCandidate(c *candidate) {
+	c.selected = true
+	for _, label := range c.labels {
+		s.achieved[label]++
+	}
+}
+
+// labels returns the labels from the rarest to the most common.
+func (s *Stratifier) labels() []string {
+	labels := make([]string, 0, len(s.labelCounts))
+	for label := range s.labelCounts {
+		labels = append(labels, label)
+	}
+	sort.Slice(labels, func(i int, j int) bool {
+		if s.labelCounts[labels[i]] == s.labelCounts[labels[j]] {
+			return labels[i] < labels[j]
+		}
+		return s.labelCounts[labels[i]] < s.labelCounts[labels[j]]
+	})
+
+	return labels
+}
+
+// Strata returns the distribution of the selected items, from the rarest to
+// the most common label.
+func (s *Stratifier) Strata() []*Stratum {
+	labels := s.labels()
+	strata := make([]*Stratum, len(labels))
+	for i, label := range labels {
+		strata[i] = &Stratum{
+			Label:     label,
+			Available: s.labelCounts[label],
+			Target:    s.targets[label],
+			Achieved:  s.achieved[label],
+		}
+	}
+
+	return strata
+}
@@ -0,0 +1,150 @@
+package process
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// stratifyItems stratifies the labelled items, returning the stratifier and
+// the selected item names.
+func stratifyItems(t *testing.T, items []*Item, mode string, quota int, count int, seed int64) (*Stratifier, map[string]bool) {
+	t.Helper()
+	sampler, err := NewSampler(1, seed, SampleModeHash)
+	if err != nil {
+		t.Fatalf("NewSampler failed: %v", err)
+	}
+	stratifier, err := NewStratifier(mode, quota, count, sampler)
+	if err != nil {
+		t.Fatalf("NewStratifier failed: %v", err)
+	}
+
+	for _, item := range items {
+		var i int
+		fmt.Sscanf(item.Name, "item-%d", &i)
+		labels := testLabels(i)
+		// unlabelled captures are never added when sampling
+		if len(labels) > 0 {
+			stratifier.Add(item, labels)
+		}
+	}
+
+	selected := make(map[string]bool)
+	for _, item := range stratifier.Select() {
+		if selected[item.Name] {
+			t.Fatalf("item %s selected more than once", item.Name)
+		}
+		selected[item.Name] = true
+	}
+
+	return stratifier, selected
+}
+
+// selectedLabelCounts counts the labels of the selected items.
+func selectedLabelCounts(selected map[string]bool) map[string]int {
+	counts := make(map[string]int)
+	for name := range selected {
+		var i int
+		fmt.Sscanf(name, "item-%d", &i)
+		for _, label := range testLabels(i) {
+			counts[label]++
+		}
+	}
+
+	return counts
+}
+
+func TestNewStratifierErrors(t *testing.T) {
+	sampler, _ := NewSampler(1, 42, SampleModeHash)
+	tests := []struct {
+		mode  string
+		quota int
+		count int
+	}{
+		{StratifyQuota, 0, 100},
+		{StratifyProportional, 10, 0},
+		{StratifyInverse, 10, 0},
+		{"balanced", 10, 100},
+	}
+
+	for _, test := range tests {
+		_, err := NewStratifier(test.mode, test.quota, test.count, sampler)
+		if err == nil {
+			t.Errorf("NewStratifier(%s, %d, %d) did not fail", test.mode, test.quota, test.count)
+		}
+	}
+}
+
+func TestStratifierQuota(t *testing.T) {
+	for _, quota := range []int{10, 50, 5000} {
+		stratifier, selected := stratifyItems(t, testItems(2000), StratifyQuota, quota, 0, 42)
+		counts := selectedLabelCounts(selected)
+
+		for _, stratum := range stratifier.Strata() {
+			// labels with fewer items than the quota are capped by availability
+			want := quota
+			if stratum.Available < want {
+				want = stratum.Available
+			}
+			if stratum.Target != want {
+				t.Errorf("quota %d label %s has target %d, want %d", quota, stratum.Label, stratum.Target, want)
+			}
+			if stratum.Achieved < stratum.Target {
+				t.Errorf("quota %d label %s achieved %d below its target %d", quota, stratum.Label, stratum.Achieved, stratum.Target)
+			}
+			if stratum.Achieved != counts[stratum.Label] {
+				t.Errorf("quota %d label %s reports %d selected, counted %d", quota, stratum.Label, stratum.Achieved, counts[stratum.Label])
+			}
+		}
+	}
+}
+
+func TestStratifierProportional(t *testing.T) {
+	count := 300
+	stratifier, selected := stratifyItems(t, testItems(5000), StratifyProportional, 0, count, 42)
+	if len(selected) != count {
+		t.Fatalf("selected %d items, want %d", len(selected), count)
+	}
+
+	// each label keeps about its share of the available items, with common
+	// labels falling short once the count is reached
+	for _, stratum := range stratifier.Strata() {
+		if diff := math.Abs(float64(stratum.Achieved - stratum.Target)); diff > math.Max(3, 0.1*float64(stratum.Target)) {
+			t.Errorf("label %s achieved %d, too far from its target %d", stratum.Label, stratum.Achieved, stratum.Target)
+		}
+	}
+}
+
+func TestStratifierReproducible(t *testing.T) {
+	modes := []struct {
+		mode  string
+		quota int
+		count int
+	}{
+		{StratifyQuota, 40, 0},
+		{StratifyProportional, 0, 200},
+		{StratifyInverse, 0, 200},
+	}
+
+	for _, m := range modes {
+		_, first := stratifyItems(t, testItems(2000), m.mode, m.quota, m.count, 42)
+		_, shuffledOrder := stratifyItems(t, shuffled(testItems(2000), 9), m.mode, m.quota, m.count, 42)
+		_, other := stratifyItems(t, testItems(2000), m.mode, m.quota, m.count, 43)
+
+		if len(shuffledOrder) != len(first) {
+			t.Fatalf("%s selected %d items then %d when added in another order", m.mode, len(first), len(shuffledOrder))
+		}
+		differences := 0
+		for name := range first {
+			if !shuffledOrder[name] {
+				t.Fatalf("%s selected %s only when added in order", m.mode, name)
+			}
+			if !other[name] {
+				differences++
+			}
+		}
+		if differences == 0 {
+			t.Errorf("%s seeds 42 and 43 selected the same items", m.mode)
+		}
+	}
+}